@@ -9,7 +9,7 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 	"jmpeax.com/guayavita/gvc/internal/codegen"
-	"jmpeax.com/guayavita/gvc/internal/fs"
+	"jmpeax.com/guayavita/gvc/internal/loader"
 	"jmpeax.com/guayavita/gvc/internal/syntax"
 	"tinygo.org/x/go-llvm"
 )
@@ -17,10 +17,10 @@ import (
 var compileCmd = &cobra.Command{
 	Use:   "compile",
 	Short: "c",
-	Long:  "Compile a guayavita file",
+	Long:  "Compile a guayavita file, or a directory holding all files of a package",
 	Args:  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 	Run: func(cmd *cobra.Command, args []string) {
-		file := args[0]
+		input := args[0]
 
 		// Check if benchmark mode is enabled
 		benchmark, _ := cmd.Flags().GetBool("benchmark")
@@ -34,31 +34,31 @@ var compileCmd = &cobra.Command{
 			runtime.ReadMemStats(&memStart)
 		}
 
-		log.Debugf("Compiling %s", file)
-		err := fs.ValidateFile(file)
+		log.Debugf("Compiling %s", input)
+		root, _ := cmd.Flags().GetString("root")
+		// Parse the entry package and every package it imports
+		program, diagnostics, err := loader.Load(input, root)
 		if err != nil {
-			log.Error(err)
-		}
-		content, err := fs.ReadFile(file)
-		if err != nil {
-			log.Errorf("Error reading file: %s", err)
+			log.Errorf("Error loading sources: %s", err)
 			return
 		}
-		// Parse the source
-		parsedFile, diagnostics := syntax.ParseFile(file, content)
 
 		// Print diagnostics if any
 		if len(diagnostics) > 0 {
 			log.Error("Parsing errors found:")
 			for _, diag := range diagnostics {
-				log.Error(diag.Render(content))
+				log.Error(diag.Render(program.Sources[diag.Span.Start.File]))
 			}
-		} else {
-			log.Info("Parsing completed successfully")
+			return
 		}
+		log.Info("Parsing completed successfully")
 
 		// Pretty-print the AST
-		log.Debugf("AST:\n%s", syntax.PrintFile(parsedFile))
+		for _, pkg := range program.Packages {
+			for _, parsedFile := range pkg.Files {
+				log.Debugf("AST:\n%s", syntax.PrintFile(parsedFile))
+			}
+		}
 
 		// Get compilation flags
 		syntaxOnly, _ := cmd.Flags().GetBool("syntax-only")
@@ -70,7 +70,7 @@ var compileCmd = &cobra.Command{
 
 			// Create and configure the code builder
 			builder := codegen.NewCodeBuilder()
-			builder.SetInputFile(file).SetOutputDir(outputDir).SetSource(content)
+			builder.SetInputFile(input).SetOutputDir(outputDir).SetSources(program.Sources)
 
 			if target != "" {
 				builder.SetTarget(target)
@@ -91,7 +91,10 @@ var compileCmd = &cobra.Command{
 			}
 
 			// Build the code
-			if err := builder.Build(parsedFile); err != nil {
+			if err := builder.Build(program.Packages); err != nil {
+				for _, diag := range builder.Diagnostics() {
+					log.Error(diag.Render(program.Sources[diag.Span.Start.File]))
+				}
 				log.Errorf("Code generation failed: %s", err)
 				return
 			}
@@ -128,7 +131,7 @@ var compileCmd = &cobra.Command{
 
 			fmt.Println()
 			fmt.Println(titleStyle.Render("Compilation Benchmark Results"))
-			fmt.Printf("%s %s\n", labelStyle.Render("File:"), fileStyle.Render(file))
+			fmt.Printf("%s %s\n", labelStyle.Render("File:"), fileStyle.Render(input))
 			fmt.Printf("%s %s\n", labelStyle.Render("Compilation Time:"), valueStyle.Render(elapsed.String()))
 			fmt.Printf("%s %s\n", labelStyle.Render("Memory Used:"), valueStyle.Render(fmt.Sprintf("%d bytes (%.2f KB)", memUsed, float64(memUsed)/1024.0)))
 			fmt.Printf("%s %s\n", labelStyle.Render("Total Allocations:"), valueStyle.Render(fmt.Sprintf("%d", memEnd.Mallocs-memStart.Mallocs)))
@@ -157,6 +160,7 @@ func init() {
 	compileCmd.Flags().Bool("jit", false, "Use JIT execution mode instead of compilation")
 	compileCmd.Flags().Bool("emit-llvm", false, "Output LLVM IR (.ll) file instead of executable binary")
 	compileCmd.Flags().StringP("output-dir", "o", "./bin", "Output directory for generated files")
+	compileCmd.Flags().String("root", "", "Root directory for resolving imports (defaults to the input directory)")
 }
 func Commands() []*cobra.Command {
 	return []*cobra.Command{compileCmd, targets}
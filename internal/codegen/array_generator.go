@@ -0,0 +1,176 @@
+package codegen
+
+import (
+	"strconv"
+
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Fixed-size arrays T[N] of primitive types are lowered to LLVM arrays and
+// passed by value. Every index is checked against the length: constant
+// indexes when compiling, others at run time.
+
+// arrayType returns the array type of n elements of elem. Array types are
+// interned so that identity stays pointer equality.
+func (b *LLVMCodeBuilder) arrayType(elem *Type, n int) *Type {
+	name := elem.Name + "[" + strconv.Itoa(n) + "]"
+	if b.arrays == nil {
+		b.arrays = make(map[string]*Type)
+	}
+	if array, ok := b.arrays[name]; ok {
+		return array
+	}
+	array := &Type{
+		Kind:   KindArray,
+		Name:   name,
+		Symbol: name,
+		Elem:   elem,
+		Len:    n,
+	}
+	b.arrays[name] = array
+	return array
+}
+
+// isPrimitive reports whether t is a primitive type other than none
+func isPrimitive(t *Type) bool {
+	return primitiveTypes[t.Name] == t && t.Kind != KindVoid
+}
+
+// generateArrayLit generates an array literal. The element type comes from
+// the expected array type, or else from the first element. Where a slice is
+// expected, the literal builds a slice instead.
+func (b *LLVMCodeBuilder) generateArrayLit(lit *syntax.ArrayLit, want *Type) (value, error) {
+	if want != nil && want.Kind == KindSlice {
+		return b.generateSliceLit(lit, want)
+	}
+
+	var elem *Type
+	if want != nil && want.Kind == KindArray {
+		if len(lit.Elements) != want.Len {
+			return value{}, b.errorAt(lit, "array literal has %d elements, but %s has %d", len(lit.Elements), want, want.Len)
+		}
+		elem = want.Elem
+	}
+	if len(lit.Elements) == 0 {
+		return value{}, b.errorAt(lit, "cannot infer the type of an empty array literal; declare an array or slice type")
+	}
+
+	elems := make([]llvm.Value, 0, len(lit.Elements))
+	constant := true
+	for _, elemExpr := range lit.Elements {
+		v, err := b.generateExprAs(elemExpr, elem)
+		if err != nil {
+			return value{}, err
+		}
+		if elem == nil {
+			if !isPrimitive(v.typ) {
+				return value{}, b.errorAt(elemExpr, "array element type must be a primitive type, got %s", v.typ)
+			}
+			elem = v.typ
+		}
+		if v, err = b.assignable(v, elem, elemExpr); err != nil {
+			return value{}, err
+		}
+		elems = append(elems, v.val)
+		constant = constant && v.val.IsConstant()
+	}
+
+	typ := b.arrayType(elem, len(elems))
+	if constant {
+		return value{llvm.ConstArray(b.llvmType(elem), elems), typ}, nil
+	}
+	agg := llvm.ConstNull(b.llvmType(typ))
+	for i, v := range elems {
+		agg = b.builder.CreateInsertValue(agg, v, i, "")
+	}
+	return value{agg, typ}, nil
+}
+
+// arrayElemAddr returns the address of the element of the array at base
+// selected by expr, after checking the index against the array length
+func (b *LLVMCodeBuilder) arrayElemAddr(expr *syntax.IndexExpr, array *Type, base llvm.Value) (llvm.Value, error) {
+	idx, err := b.generateOffset(expr.Index)
+	if err != nil {
+		return llvm.Value{}, err
+	}
+	length := llvm.ConstInt(b.context.Int64Type(), uint64(array.Len), false)
+	if idx.IsConstant() {
+		if idx.ZExtValue() >= uint64(array.Len) {
+			return llvm.Value{}, b.errorAt(expr.Index, "index %d out of range for %s", idx.SExtValue(), array)
+		}
+	} else {
+		b.generateCheck(b.builder.CreateICmp(llvm.IntULT, idx, length, "inbounds"), expr, "index out of range for "+array.Name)
+	}
+	zero := llvm.ConstInt(b.context.Int64Type(), 0, false)
+	return b.builder.CreateGEP(b.llvmType(array), base, []llvm.Value{zero, idx}, "elem"), nil
+}
+
+// generateArrayIndex reads an element of an array that is not addressable,
+// such as the result of a call
+func (b *LLVMCodeBuilder) generateArrayIndex(expr *syntax.IndexExpr, array value) (value, error) {
+	tmp := b.createEntryAlloca(b.llvmType(array.typ), "array")
+	b.builder.CreateStore(array.val, tmp)
+	ptr, err := b.arrayElemAddr(expr, array.typ, tmp)
+	if err != nil {
+		return value{}, err
+	}
+	return value{b.builder.CreateLoad(b.llvmType(array.typ.Elem), ptr, "elem"), array.typ.Elem}, nil
+}
+
+// generateArrayLoop lowers a for-in loop over an array. Like any array
+// value, the array is copied, so the body may assign its elements without
+// affecting the iteration.
+func (b *LLVMCodeBuilder) generateArrayLoop(stmt *syntax.ForInStmt, array value) error {
+	arrayType := b.llvmType(array.typ)
+	tmp := b.createEntryAlloca(arrayType, stmt.Var+".array")
+	b.builder.CreateStore(array.val, tmp)
+
+	length := llvm.ConstInt(b.context.Int64Type(), uint64(array.typ.Len), false)
+	zero := llvm.ConstInt(b.context.Int64Type(), 0, false)
+	return b.generateIndexLoop(stmt, length, array.typ.Elem, func(i llvm.Value) llvm.Value {
+		return b.builder.CreateGEP(arrayType, tmp, []llvm.Value{zero, i}, "elem")
+	})
+}
+
+// generateIndexLoop lowers a for-in loop over length elements, where elemAddr
+// returns the address of the element at an i64 index. The loop variable is a
+// copy of the element.
+func (b *LLVMCodeBuilder) generateIndexLoop(stmt *syntax.ForInStmt, length llvm.Value, elem *Type, elemAddr func(i llvm.Value) llvm.Value) error {
+	i64 := b.context.Int64Type()
+	counter := b.createEntryAlloca(i64, stmt.Var+".index")
+	b.builder.CreateStore(llvm.ConstInt(i64, 0, false), counter)
+
+	condBlock := b.context.AddBasicBlock(b.fn.value, "forin.cond")
+	bodyBlock := b.context.AddBasicBlock(b.fn.value, "forin.body")
+	postBlock := b.context.AddBasicBlock(b.fn.value, "forin.post")
+	exitBlock := b.context.AddBasicBlock(b.fn.value, "forin.end")
+
+	b.builder.CreateBr(condBlock)
+	b.builder.SetInsertPointAtEnd(condBlock)
+	current := b.builder.CreateLoad(i64, counter, "i")
+	b.builder.CreateCondBr(b.builder.CreateICmp(llvm.IntULT, current, length, "cmp"), bodyBlock, exitBlock)
+
+	b.builder.SetInsertPointAtEnd(bodyBlock)
+	b.pushScope()
+	elemType := b.llvmType(elem)
+	ptr := b.createLocal(elem, stmt.Var)
+	b.builder.CreateStore(b.builder.CreateLoad(elemType, elemAddr(current), stmt.Var), ptr)
+	b.fn.scope.define(&symbol{kind: symbolVar, name: stmt.Var, typ: elem, ptr: ptr, pkg: b.pkg, decl: stmt})
+	err := b.generateLoopBody(stmt, stmt.Label, stmt.Body, nil, exitBlock, postBlock)
+	b.popScope()
+	if err != nil {
+		return err
+	}
+	if !b.isTerminated() {
+		b.builder.CreateBr(postBlock)
+	}
+
+	b.builder.SetInsertPointAtEnd(postBlock)
+	current = b.builder.CreateLoad(i64, counter, "i")
+	b.builder.CreateStore(b.builder.CreateAdd(current, llvm.ConstInt(i64, 1, false), "next"), counter)
+	b.builder.CreateBr(condBlock)
+
+	b.builder.SetInsertPointAtEnd(exitBlock)
+	return nil
+}
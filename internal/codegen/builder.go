@@ -24,7 +24,7 @@ type BuilderConfig struct {
 	Target    string // LLVM target triple
 	OutputDir string
 	InputFile string
-	Source    string // original source content for diagnostics rendering
+	Sources   map[string]string // original source content by file name, for diagnostics rendering
 }
 
 // CodeBuilder interface defines the builder pattern for code generation
@@ -33,8 +33,8 @@ type CodeBuilder interface {
 	SetMode(mode CompilationMode) CodeBuilder
 	SetOutputDir(dir string) CodeBuilder
 	SetInputFile(file string) CodeBuilder
-	SetSource(src string) CodeBuilder
-	Build(ast *syntax.File) error
+	SetSources(sources map[string]string) CodeBuilder
+	Build(pkgs []*syntax.Package) error
 	Diagnostics() []diag.Diagnostic
 	SetDefaultTarget()
 }
@@ -48,6 +48,21 @@ type LLVMCodeBuilder struct {
 	externals       *ExternalRegistry
 	printedLiterals []string // Track string literals for wrapper script
 	diagnostics     []diag.Diagnostic
+	universe        *scope                   // built-in declarations
+	resultType      *Type                    // the built-in generic Result<T, E>
+	packages        map[string]*packageScope // declared packages by path
+	pkg             *packageScope            // package being generated
+	fn              *funcState               // function being generated
+	structTypes     map[*Type]llvm.Type      // named LLVM struct types
+	optionals       map[*Type]*Type          // optional types by element type
+	tuples          map[string]*Type         // tuple types by element types
+	arrays          map[string]*Type         // array types by element type and length
+	slices          map[*Type]*Type          // slice types by element type
+	funcs           map[string]*Type         // function types by signature
+	thunks          map[*function]llvm.Value // closure entry points of named functions
+	vtableTypes     map[*Type]llvm.Type      // vtable layouts by interface
+	vtables         map[[2]*Type]llvm.Value  // vtables by implementing type and interface
+	instances       []*function              // instantiations awaiting a body
 }
 
 // NewCodeBuilder creates a new LLVM-based code builder
@@ -79,9 +94,9 @@ func (b *LLVMCodeBuilder) SetInputFile(file string) CodeBuilder {
 	return b
 }
 
-// SetSource sets the original source content for diagnostics rendering
-func (b *LLVMCodeBuilder) SetSource(src string) CodeBuilder {
-	b.config.Source = src
+// SetSources sets the original source content of every file for diagnostics rendering
+func (b *LLVMCodeBuilder) SetSources(sources map[string]string) CodeBuilder {
+	b.config.Sources = sources
 	return b
 }
 
@@ -90,8 +105,9 @@ func (b *LLVMCodeBuilder) Diagnostics() []diag.Diagnostic {
 	return b.diagnostics
 }
 
-// Build compiles the AST according to the builder configuration
-func (b *LLVMCodeBuilder) Build(ast *syntax.File) error {
+// Build compiles the packages according to the builder configuration.
+// Packages must be ordered dependencies first, with the entry package last.
+func (b *LLVMCodeBuilder) Build(pkgs []*syntax.Package) error {
 	llvm.InitializeAllTargetInfos()
 	llvm.InitializeAllTargets()
 	llvm.InitializeAllTargetMCs()
@@ -106,11 +122,11 @@ func (b *LLVMCodeBuilder) Build(ast *syntax.File) error {
 	defer b.cleanup()
 
 	// Generate LLVM IR from AST
-	if err := b.generateIR(ast); err != nil {
-		// Attach to file position if available
+	if err := b.generateIR(pkgs); err != nil {
+		// Attach to the entry package position if available
 		pos := diag.Position{File: b.config.InputFile, Line: 1, Column: 1}
-		if ast != nil {
-			pos = ast.Pos()
+		if len(pkgs) > 0 && len(pkgs[len(pkgs)-1].Files) > 0 {
+			pos = pkgs[len(pkgs)-1].Files[0].Pos()
 		}
 		b.addDiagnostic(diag.Error, pos, fmt.Sprintf("failed to generate LLVM IR: %v", err))
 		return err
@@ -0,0 +1,386 @@
+package codegen
+
+import (
+	"slices"
+	"strings"
+
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Function values are closures, lowered to the pair {code, env}. The code
+// takes env as an i8* before its declared parameters; env points to a struct
+// holding the addresses of the captured variables, or is null. Variables
+// are captured by reference: locals used by function literals live on the
+// heap (see createLocal), so assignments through a closure are seen by the
+// enclosing function and by every closure sharing the variable. Named
+// functions are used as values through a thunk that ignores env.
+//
+// There is no garbage collector: like environments, slices and strings,
+// these variables are never freed, so each execution of their declaration,
+// in a loop included, leaks one allocation.
+
+// funcType returns the function type of the given signature. Function types
+// are interned so that identity stays pointer equality.
+func (b *LLVMCodeBuilder) funcType(params []*Type, result *Type) *Type {
+	names := make([]string, 0, len(params))
+	keys := make([]string, 0, len(params))
+	for _, param := range params {
+		names = append(names, param.String())
+		keys = append(keys, mangledTypeName(param))
+	}
+	key := "fun(" + strings.Join(keys, ",") + "):" + mangledTypeName(result)
+
+	if b.funcs == nil {
+		b.funcs = make(map[string]*Type)
+	}
+	if fn, ok := b.funcs[key]; ok {
+		return fn
+	}
+	fn := &Type{
+		Kind:   KindFunc,
+		Name:   "fun(" + strings.Join(names, ", ") + ") : " + result.String(),
+		Symbol: key,
+		Params: params,
+		Result: result,
+	}
+	b.funcs[key] = fn
+	return fn
+}
+
+// closureFnType returns the LLVM type of the code of closures of type t
+func (b *LLVMCodeBuilder) closureFnType(t *Type) llvm.Type {
+	params := []llvm.Type{llvm.PointerType(b.context.Int8Type(), 0)}
+	for _, param := range t.Params {
+		params = append(params, b.llvmType(param))
+	}
+	return llvm.FunctionType(b.llvmType(t.Result), params, false)
+}
+
+// buildClosure pairs the code of a closure with its environment
+func (b *LLVMCodeBuilder) buildClosure(t *Type, code, env llvm.Value) llvm.Value {
+	closure := llvm.ConstNull(b.llvmType(t))
+	closure = b.builder.CreateInsertValue(closure, code, 0, "")
+	return b.builder.CreateInsertValue(closure, env, 1, "closure")
+}
+
+// envType returns the layout of the environment holding the addresses of
+// captures
+func (b *LLVMCodeBuilder) envType(captures []*symbol) llvm.Type {
+	fields := make([]llvm.Type, 0, len(captures))
+	for _, sym := range captures {
+		fields = append(fields, sym.ptr.Type())
+	}
+	return b.context.StructType(fields, false)
+}
+
+// functionValue returns a named function as a closure without environment
+func (b *LLVMCodeBuilder) functionValue(fn *function, node syntax.Node) (value, error) {
+	if fn.isGeneric() {
+		return value{}, b.errorAt(node, "cannot use generic function %s as value", fn.name)
+	}
+	typ := b.funcType(fn.params, fn.result)
+	i8Ptr := llvm.PointerType(b.context.Int8Type(), 0)
+	return value{b.buildClosure(typ, b.thunk(fn, typ), llvm.ConstNull(i8Ptr)), typ}, nil
+}
+
+// thunk returns the entry point of fn as the code of a closure of type t,
+// which forwards its parameters to fn
+func (b *LLVMCodeBuilder) thunk(fn *function, t *Type) llvm.Value {
+	if thunk, ok := b.thunks[fn]; ok {
+		return thunk
+	}
+	if b.thunks == nil {
+		b.thunks = make(map[*function]llvm.Value)
+	}
+
+	thunk := llvm.AddFunction(b.module, fn.value.Name()+".closure", b.closureFnType(t))
+	thunk.SetLinkage(llvm.InternalLinkage)
+	builder := b.context.NewBuilder()
+	defer builder.Dispose()
+	builder.SetInsertPointAtEnd(b.context.AddBasicBlock(thunk, "entry"))
+
+	args := thunk.Params()[1:]
+	if fn.result.Kind == KindVoid {
+		builder.CreateCall(fn.fnType, fn.value, args, "")
+		builder.CreateRetVoid()
+	} else {
+		builder.CreateRet(builder.CreateCall(fn.fnType, fn.value, args, "call"))
+	}
+
+	b.thunks[fn] = thunk
+	return thunk
+}
+
+// generateFuncLit generates an anonymous function as a closure. Its body
+// resolves names in the scope of the enclosing declaration, so locals of the
+// enclosing function are only visible through the environment.
+func (b *LLVMCodeBuilder) generateFuncLit(lit *syntax.FuncLit) (value, error) {
+	fn := &function{
+		name:    "function literal",
+		decl:    &syntax.FunDecl{Name: "function literal", Params: lit.Params, Type: lit.Type, Body: lit.Body, Pos_: lit.Pos_},
+		pkg:     b.pkg,
+		scope:   b.fn.scope,
+		parent:  b.fn.fn,
+		closure: true,
+	}
+	if b.fn.fn != nil {
+		fn.scope = b.fn.fn.scope
+	}
+
+	paramNames := make(map[string]bool, len(lit.Params))
+	for i := range lit.Params {
+		param := &lit.Params[i]
+		if param.Type == nil {
+			return value{}, b.errorAt(param, "%s can only be the first parameter of a method", syntax.ReceiverName)
+		}
+		typ, err := b.resolveType(param.Type, b.fn.scope)
+		if err != nil {
+			return value{}, err
+		}
+		fn.params = append(fn.params, typ)
+		paramNames[param.Name] = true
+	}
+	result, err := b.resolveType(lit.Type, b.fn.scope)
+	if err != nil {
+		return value{}, err
+	}
+	fn.result = result
+	typ := b.funcType(fn.params, result)
+
+	// Locals used by the body, including nested function literals, are
+	// captured; globals are shared
+	for _, name := range usedNames(lit.Body, nil) {
+		sym := b.fn.scope.lookup(name)
+		if paramNames[name] || sym == nil || sym.kind != symbolVar || !sym.ptr.IsAGlobalVariable().IsNil() {
+			continue
+		}
+		fn.captures = append(fn.captures, sym)
+	}
+
+	name := b.pkg.mangle("lambda")
+	if b.fn.fn != nil {
+		name = b.fn.value.Name() + ".lambda"
+	}
+	fn.fnType = b.closureFnType(typ)
+	fn.value = llvm.AddFunction(b.module, name, fn.fnType)
+	fn.value.SetLinkage(llvm.InternalLinkage)
+
+	env := llvm.ConstNull(llvm.PointerType(b.context.Int8Type(), 0))
+	if len(fn.captures) > 0 {
+		envType := b.envType(fn.captures)
+		td := llvm.NewTargetData(b.module.DataLayout())
+		size := td.TypeAllocSize(envType)
+		td.Dispose()
+
+		env = b.callExternal("malloc", llvm.ConstInt(b.context.Int64Type(), size, false))
+		fields := b.builder.CreateBitCast(env, llvm.PointerType(envType, 0), "env")
+		for i, sym := range fn.captures {
+			b.builder.CreateStore(sym.ptr, b.builder.CreateStructGEP(envType, fields, i, ""))
+		}
+	}
+
+	if err := b.generateFunctionBody(fn, fn.scope); err != nil {
+		return value{}, err
+	}
+	return value{b.buildClosure(typ, fn.value, env), typ}, nil
+}
+
+// bindCaptures defines the captured variables of a closure being generated
+// at the addresses held by its environment
+func (b *LLVMCodeBuilder) bindCaptures(fn *function) {
+	if len(fn.captures) == 0 {
+		return
+	}
+	envType := b.envType(fn.captures)
+	env := b.builder.CreateBitCast(fn.value.Param(0), llvm.PointerType(envType, 0), "env")
+	for i, sym := range fn.captures {
+		b.fn.scope.define(&symbol{
+			kind: symbolVar,
+			name: sym.name,
+			typ:  sym.typ,
+			ptr:  b.builder.CreateLoad(sym.ptr.Type(), b.builder.CreateStructGEP(envType, env, i, ""), sym.name),
+			pkg:  sym.pkg,
+			decl: sym.decl,
+		})
+	}
+}
+
+// generateClosureCall calls a function value
+func (b *LLVMCodeBuilder) generateClosureCall(expr *syntax.CallExpr, callee value) (value, error) {
+	typ := callee.typ
+	if typ.Kind != KindFunc {
+		return value{}, b.errorAt(expr, "cannot call non-function %s of type %s", exprName(expr.Fun), typ)
+	}
+	if len(expr.Args) != len(typ.Params) {
+		return value{}, b.errorAt(expr, "function value %s expects %d arguments, got %d", exprName(expr.Fun), len(typ.Params), len(expr.Args))
+	}
+
+	args := []llvm.Value{b.builder.CreateExtractValue(callee.val, 1, "env")}
+	for i, arg := range expr.Args {
+		v, err := b.generateExprAs(arg, typ.Params[i])
+		if err != nil {
+			return value{}, err
+		}
+		if v, err = b.assignable(v, typ.Params[i], arg); err != nil {
+			return value{}, err
+		}
+		args = append(args, v.val)
+	}
+
+	name := "call"
+	if typ.Result.Kind == KindVoid {
+		name = ""
+	}
+	code := b.builder.CreateExtractValue(callee.val, 0, "code")
+	return value{b.builder.CreateCall(b.closureFnType(typ), code, args, name), typ.Result}, nil
+}
+
+// usedNames appends to names every identifier node refers to, in order of
+// first use
+func usedNames(node syntax.Node, names []string) []string {
+	inspect(node, func(n syntax.Node) bool {
+		if ident, ok := n.(*syntax.Ident); ok && !slices.Contains(names, ident.Name) {
+			names = append(names, ident.Name)
+		}
+		return true
+	})
+	return names
+}
+
+// capturedNames returns the names used by the function literals in body,
+// which are the locals of its function that closures may capture
+func capturedNames(body *syntax.Block) map[string]bool {
+	captured := make(map[string]bool)
+	inspect(body, func(n syntax.Node) bool {
+		lit, ok := n.(*syntax.FuncLit)
+		if !ok {
+			return true
+		}
+		for _, name := range usedNames(lit.Body, nil) {
+			captured[name] = true
+		}
+		return false
+	})
+	return captured
+}
+
+// reassignedNames returns the names assigned by the function literals in
+// body. Captured locals with these names may change at any call.
+func reassignedNames(body *syntax.Block) map[string]bool {
+	reassigned := make(map[string]bool)
+	inspect(body, func(n syntax.Node) bool {
+		lit, ok := n.(*syntax.FuncLit)
+		if !ok {
+			return true
+		}
+		inspect(lit.Body, func(n syntax.Node) bool {
+			if stmt, ok := n.(*syntax.AssignStmt); ok {
+				if ident, ok := stmt.Left.(*syntax.Ident); ok {
+					reassigned[ident.Name] = true
+				}
+			}
+			return true
+		})
+		return false
+	})
+	return reassigned
+}
+
+// inspect calls visit for node and, unless visit returns false, for the
+// statements and expressions it contains, in source order
+func inspect(node syntax.Node, visit func(syntax.Node) bool) {
+	if node == nil || !visit(node) {
+		return
+	}
+	use := func(n syntax.Node) {
+		inspect(n, visit)
+	}
+	switch n := node.(type) {
+	case *syntax.Block:
+		if n == nil {
+			return
+		}
+		for _, stmt := range n.Stmts {
+			use(stmt)
+		}
+	case *syntax.VarDecl:
+		use(n.Init)
+	case *syntax.AssignStmt:
+		use(n.Left)
+		use(n.Right)
+	case *syntax.ExprStmt:
+		use(n.X)
+	case *syntax.ReturnStmt:
+		use(n.Result)
+	case *syntax.IfStmt:
+		use(n.Cond)
+		use(n.Body)
+		use(n.Else)
+	case *syntax.WhileStmt:
+		use(n.Cond)
+		use(n.Body)
+	case *syntax.ForStmt:
+		use(n.Init)
+		use(n.Cond)
+		use(n.Post)
+		use(n.Body)
+	case *syntax.ForInStmt:
+		use(n.Iter)
+		use(n.Body)
+	case *syntax.MatchStmt:
+		use(n.X)
+		for _, arm := range n.Arms {
+			use(arm.Body)
+		}
+		use(n.Else)
+	case *syntax.HandleStmt:
+		use(n.X)
+		if n.Ok != nil {
+			use(n.Ok.Body)
+		}
+		if n.Err != nil {
+			use(n.Err.Body)
+		}
+	case *syntax.TupleExpr:
+		for _, elem := range n.Elems {
+			use(elem)
+		}
+	case *syntax.RangeExpr:
+		use(n.Low)
+		use(n.High)
+	case *syntax.IndexExpr:
+		use(n.X)
+		use(n.Index)
+	case *syntax.BinaryExpr:
+		use(n.Left)
+		use(n.Right)
+	case *syntax.UnaryExpr:
+		use(n.X)
+	case *syntax.CastExpr:
+		use(n.X)
+	case *syntax.CallExpr:
+		use(n.Fun)
+		for _, arg := range n.Args {
+			use(arg)
+		}
+	case *syntax.TryExpr:
+		use(n.X)
+	case *syntax.SelectorExpr:
+		use(n.X)
+	case *syntax.InterpolatedString:
+		for _, part := range n.Parts {
+			use(part)
+		}
+	case *syntax.ArrayLit:
+		for _, elem := range n.Elements {
+			use(elem)
+		}
+	case *syntax.StructLit:
+		for _, field := range n.Fields {
+			use(field.Value)
+		}
+	case *syntax.FuncLit:
+		use(n.Body)
+	}
+}
@@ -0,0 +1,92 @@
+package codegen
+
+import (
+	"fmt"
+
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Conversions x as T between numeric types never fail:
+//   - integers are truncated to narrower types and extended to wider ones,
+//     with the sign when the source is signed; between types of the same
+//     width the bits are kept, so -1 as u8 is 255
+//   - integers become the nearest float
+//   - floats are truncated toward zero to integers, saturating at the bounds
+//     of the target type; NaN becomes 0
+//   - floats are rounded to f32 and extended to f64
+//   - bools become the integers 1 or 0
+// Values of different types never mix implicitly, so conversions are how
+// widths are changed.
+
+// generateCastExpr generates a conversion, x as T. An untyped literal is
+// generated as T directly, so it must be representable in T.
+func (b *LLVMCodeBuilder) generateCastExpr(expr *syntax.CastExpr) (value, error) {
+	target, err := b.resolveType(expr.Type, b.fn.scope)
+	if err != nil {
+		return value{}, err
+	}
+	if !target.IsNumeric() {
+		return value{}, b.errorAt(expr.Type, "cannot convert to non-numeric type %s", target)
+	}
+	x, err := b.generateExprAs(expr.X, target)
+	if err != nil {
+		return value{}, err
+	}
+	if x.typ == target {
+		return x, nil
+	}
+
+	src := x.typ
+	dst := b.llvmType(target)
+	switch {
+	case src.Kind == KindBool && target.Kind == KindInt:
+		return value{b.builder.CreateZExt(x.val, dst, "conv"), target}, nil
+	case src.Kind == KindInt && target.Kind == KindInt:
+		return value{b.convertInt(x.val, src, target), target}, nil
+	case src.Kind == KindInt && src.Signed:
+		return value{b.builder.CreateSIToFP(x.val, dst, "conv"), target}, nil
+	case src.Kind == KindInt:
+		return value{b.builder.CreateUIToFP(x.val, dst, "conv"), target}, nil
+	case src.Kind == KindFloat && target.Kind == KindInt:
+		return value{b.saturatingFloatToInt(x.val, src, target), target}, nil
+	case src.Kind == KindFloat && src.Bits < target.Bits:
+		return value{b.builder.CreateFPExt(x.val, dst, "conv"), target}, nil
+	case src.Kind == KindFloat:
+		return value{b.builder.CreateFPTrunc(x.val, dst, "conv"), target}, nil
+	}
+	return value{}, b.errorAt(expr, "cannot convert value of type %s to %s", src, target)
+}
+
+// convertInt truncates or extends an integer of type from to type to
+func (b *LLVMCodeBuilder) convertInt(v llvm.Value, from, to *Type) llvm.Value {
+	dst := b.llvmType(to)
+	switch {
+	case from.Bits > to.Bits:
+		return b.builder.CreateTrunc(v, dst, "conv")
+	case from.Bits == to.Bits:
+		return v
+	case from.Signed:
+		return b.builder.CreateSExt(v, dst, "conv")
+	default:
+		return b.builder.CreateZExt(v, dst, "conv")
+	}
+}
+
+// saturatingFloatToInt converts a float to an integer type with the
+// llvm.fpto[su]i.sat intrinsics, which clamp values out of range and map NaN
+// to 0
+func (b *LLVMCodeBuilder) saturatingFloatToInt(v llvm.Value, from, to *Type) llvm.Value {
+	op := "fptoui"
+	if to.Signed {
+		op = "fptosi"
+	}
+	dst := b.llvmType(to)
+	name := fmt.Sprintf("llvm.%s.sat.i%d.f%d", op, to.Bits, from.Bits)
+	fnType := llvm.FunctionType(dst, []llvm.Type{b.llvmType(from)}, false)
+	fn := b.module.NamedFunction(name)
+	if fn.IsNil() {
+		fn = llvm.AddFunction(b.module, name, fnType)
+	}
+	return b.builder.CreateCall(fnType, fn, []llvm.Value{v}, "conv")
+}
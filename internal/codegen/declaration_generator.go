@@ -2,6 +2,7 @@ package codegen
 
 import (
 	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
 )
 
 // generateDecl generates LLVM IR for a declaration
@@ -11,39 +12,254 @@ func (b *LLVMCodeBuilder) generateDecl(decl syntax.Decl) error {
 		return b.generateFunctionDecl(d)
 	case *syntax.VarDecl:
 		return b.generateVarDecl(d)
+	case *syntax.TypeDecl:
+		// Types are fully declared by declarePackage
+		return nil
+	case *syntax.ImplDecl:
+		return b.generateImplDecl(d)
 	default:
 		return b.errorAt(d, "unsupported declaration type: %T", decl)
 	}
 }
 
-// generateFunctionDecl generates LLVM IR for a function declaration
+// declareFunction adds the LLVM prototype of a function to the module and
+// registers it in the package scope
+func (b *LLVMCodeBuilder) declareFunction(ps *packageScope, fileScope *scope, decl *syntax.FunDecl) error {
+	if ps.scope.lookupLocal(decl.Name) != nil {
+		return b.errorAt(decl, "%s redeclared in package %s", decl.Name, ps.path)
+	}
+	if decl.HasReceiver() {
+		return b.errorAt(decl, "function %s takes %s outside of an impl block", decl.Name, syntax.ReceiverName)
+	}
+
+	// Generic functions are instantiated at their call sites
+	fn := &function{name: decl.Name, decl: decl, pkg: ps, scope: fileScope}
+	if fn.isGeneric() {
+		if err := b.checkBoundTypes(decl.Bounds, fileScope); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if fn, err = b.newFunction(ps, fileScope, decl, nil, ps.mangle(decl.Name)); err != nil {
+			return err
+		}
+		fn.value.SetLinkage(linkage(decl.Exported))
+	}
+
+	ps.scope.define(&symbol{
+		kind: symbolFunc,
+		name: decl.Name,
+		fn:   fn,
+		pkg:  ps,
+		decl: decl,
+	})
+
+	return nil
+}
+
+// newFunction resolves the signature of decl and adds its LLVM prototype
+// under the given symbol name. Methods taking self receive a pointer to recv
+// as their first LLVM parameter.
+func (b *LLVMCodeBuilder) newFunction(ps *packageScope, fileScope *scope, decl *syntax.FunDecl, recv *Type, symbolName string) (*function, error) {
+	fn := &function{
+		name:  decl.Name,
+		decl:  decl,
+		pkg:   ps,
+		scope: fileScope,
+	}
+
+	paramTypes := make([]llvm.Type, 0, len(decl.Params))
+	for i := range decl.Params {
+		param := &decl.Params[i]
+		if param.Type == nil {
+			if i != 0 || recv == nil {
+				return nil, b.errorAt(param, "%s must be the first parameter of a method", syntax.ReceiverName)
+			}
+			fn.recv = recv
+			paramTypes = append(paramTypes, llvm.PointerType(b.llvmType(recv), 0))
+			continue
+		}
+		typ, err := b.resolveType(param.Type, fileScope)
+		if err != nil {
+			return nil, err
+		}
+		fn.params = append(fn.params, typ)
+		paramTypes = append(paramTypes, b.llvmType(typ))
+	}
+
+	result, err := b.resolveType(decl.Type, fileScope)
+	if err != nil {
+		return nil, err
+	}
+	fn.result = result
+
+	fn.fnType = llvm.FunctionType(b.llvmType(result), paramTypes, false)
+	fn.value = llvm.AddFunction(b.module, symbolName, fn.fnType)
+
+	return fn, nil
+}
+
+// generateFunctionDecl generates the body of a function declared by declareFunction
 func (b *LLVMCodeBuilder) generateFunctionDecl(decl *syntax.FunDecl) error {
-	// For now, skip non-main functions - this is a basic implementation
-	if decl.Name != "main" {
+	sym := b.pkg.scope.lookupLocal(decl.Name)
+	if sym == nil || sym.kind != symbolFunc {
+		return b.errorAt(decl, "function %s was not declared", decl.Name)
+	}
+	if sym.fn.isGeneric() {
+		// Bodies of generic functions are generated per instantiation
 		return nil
 	}
+	return b.generateFunctionBody(sym.fn, b.fn.scope)
+}
+
+// generateFunctionBody emits the body of fn. Parameters live in a scope nested
+// in parent, the scope of the file declaring the function.
+func (b *LLVMCodeBuilder) generateFunctionBody(fn *function, parent *scope) error {
+	savedFn := b.fn
+	savedBlock := b.builder.GetInsertBlock()
+	defer func() {
+		b.fn = savedFn
+		if !savedBlock.IsNil() {
+			b.builder.SetInsertPointAtEnd(savedBlock)
+		}
+	}()
+
+	// A function literal shares the variables its enclosing declaration
+	// and sibling literals assign
+	reassigned := reassignedNames(fn.decl.Body)
+	if fn.closure && savedFn != nil {
+		reassigned = savedFn.reassigned
+	}
+	b.fn = &funcState{
+		fn:         fn,
+		value:      fn.value,
+		scope:      newScope(parent),
+		captured:   capturedNames(fn.decl.Body),
+		reassigned: reassigned,
+	}
+
+	entry := b.context.AddBasicBlock(fn.value, "entry")
+	b.builder.SetInsertPointAtEnd(entry)
+
+	// Closures find their captured variables in the environment, which
+	// precedes the declared parameters
+	first := 0
+	if fn.closure {
+		first = 1
+		b.bindCaptures(fn)
+	}
+
+	// Parameters are spilled to stack slots so they behave like locals. The
+	// receiver already is a pointer and is addressed in place.
+	params := fn.params
+	for i, param := range fn.decl.Params {
+		sym := &symbol{
+			kind: symbolVar,
+			name: param.Name,
+			pkg:  fn.pkg,
+			decl: &fn.decl.Params[i],
+		}
+		if fn.recv != nil && i == 0 {
+			sym.typ = fn.recv
+			sym.ptr = fn.value.Param(0)
+		} else {
+			sym.typ = params[0]
+			params = params[1:]
+			sym.ptr = b.createLocal(sym.typ, param.Name)
+			b.builder.CreateStore(fn.value.Param(first+i), sym.ptr)
+		}
+		b.fn.scope.define(sym)
+	}
+
+	if fn.decl.Body != nil {
+		if err := b.generateBlock(fn.decl.Body); err != nil {
+			return err
+		}
+	}
 
-	// Generate statements in function body
-	if decl.Body != nil {
-		return b.generateBlock(decl.Body)
+	if b.isTerminated() {
+		return nil
+	}
+	switch {
+	case fn.result.Kind == KindVoid:
+		b.builder.CreateRetVoid()
+	case !b.isReachable():
+		b.builder.CreateUnreachable()
+	default:
+		return b.errorAt(fn.decl, "missing return at end of function %s", fn.name)
 	}
 
 	return nil
 }
 
-// generateVarDecl generates LLVM IR for a variable declaration
+// generateVarDecl generates LLVM IR for a variable declaration. Outside of a
+// function body the variable becomes a package global.
 func (b *LLVMCodeBuilder) generateVarDecl(decl *syntax.VarDecl) error {
-	// Basic variable declaration - allocate space and store initial value
-	varType := b.context.Int32Type() // Default to int32 for now
-	alloca := b.builder.CreateAlloca(varType, decl.Name)
+	declScope := b.fn.scope
+	if b.fn.fn == nil {
+		// Globals are bound in the package scope so every file can see them
+		declScope = b.pkg.scope
+	}
 
-	if decl.Init != nil {
-		value, err := b.generateExpr(decl.Init)
+	var declared *Type
+	if decl.Type != nil {
+		typ, err := b.resolveType(decl.Type, b.fn.scope)
 		if err != nil {
 			return err
 		}
-		b.builder.CreateStore(value, alloca)
+		declared = typ
+	}
+	if len(decl.Names) > 0 {
+		return b.generateDestructuring(decl, declared, declScope)
+	}
+
+	if declScope.lookupLocal(decl.Name) != nil {
+		return b.errorAt(decl, "%s redeclared in this block", decl.Name)
 	}
 
+	init, err := b.generateExprAs(decl.Init, declared)
+	if err != nil {
+		return err
+	}
+	if declared != nil {
+		if init, err = b.assignable(init, declared, decl.Init); err != nil {
+			return err
+		}
+	}
+	if init.typ.Kind == KindVoid {
+		return b.errorAt(decl.Init, "%s has no value to assign to %s", init.typ, decl.Name)
+	}
+
+	b.defineVar(decl, decl.Name, init, declScope)
 	return nil
 }
+
+// defineVar stores the initial value of a variable in a new local, or in a
+// global outside of a function body, and binds it in declScope
+func (b *LLVMCodeBuilder) defineVar(decl *syntax.VarDecl, name string, init value, declScope *scope) {
+	llvmType := b.llvmType(init.typ)
+	var ptr llvm.Value
+	if b.fn.fn == nil {
+		ptr = llvm.AddGlobal(b.module, llvmType, b.pkg.mangle(name))
+		ptr.SetLinkage(linkage(decl.Exported))
+		if init.val.IsConstant() {
+			ptr.SetInitializer(init.val)
+		} else {
+			ptr.SetInitializer(llvm.ConstNull(llvmType))
+			b.builder.CreateStore(init.val, ptr)
+		}
+	} else {
+		ptr = b.createLocal(init.typ, name)
+		b.builder.CreateStore(init.val, ptr)
+	}
+
+	declScope.define(&symbol{
+		kind:     symbolVar,
+		name:     name,
+		typ:      init.typ,
+		ptr:      ptr,
+		pkg:      b.pkg,
+		decl:     decl,
+		constant: b.fn.fn == nil,
+	})
+}
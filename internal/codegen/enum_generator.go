@@ -0,0 +1,315 @@
+package codegen
+
+import (
+	"strings"
+
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Enums are lowered to tagged unions: a named struct holding an i32 tag and
+// a word array large enough for the biggest payload. A variant payload is
+// read and written through a bitcast of the array to the variant layout.
+
+// resolveEnumVariants resolves the payload types of an enum declaration
+func (b *LLVMCodeBuilder) resolveEnumVariants(typ *Type, et *syntax.EnumType, sc *scope) error {
+	if len(et.Variants) == 0 {
+		return b.errorAt(et, "enum %s has no variants", typ.Name)
+	}
+
+	for i := range et.Variants {
+		variant := &et.Variants[i]
+		if typ.VariantIndex(variant.Name) >= 0 {
+			return b.errorAt(variant, "duplicate variant %s in enum %s", variant.Name, typ.Name)
+		}
+		payload := make([]*Type, 0, len(variant.Types))
+		for _, typeExpr := range variant.Types {
+			t, err := b.resolveType(typeExpr, sc)
+			if err != nil {
+				return err
+			}
+			if t.Kind == KindVoid {
+				return b.errorAt(variant, "variant %s cannot hold type %s", variant.Name, t)
+			}
+			payload = append(payload, t)
+		}
+		typ.Variants = append(typ.Variants, EnumVariant{Name: variant.Name, Payload: payload})
+	}
+	return nil
+}
+
+// enumType returns the named LLVM struct of an enum, creating it on first use
+func (b *LLVMCodeBuilder) enumType(t *Type) llvm.Type {
+	if b.structTypes == nil {
+		b.structTypes = make(map[*Type]llvm.Type)
+	}
+	if st, ok := b.structTypes[t]; ok {
+		return st
+	}
+
+	st := b.context.StructCreateNamed(t.Symbol)
+	b.structTypes[t] = st
+
+	td := llvm.NewTargetData(b.module.DataLayout())
+	defer td.Dispose()
+
+	var size uint64
+	for i := range t.Variants {
+		if s := td.TypeAllocSize(b.payloadType(t, i)); s > size {
+			size = s
+		}
+	}
+
+	elements := []llvm.Type{b.context.Int32Type()}
+	if words := (size + 7) / 8; words > 0 {
+		elements = append(elements, llvm.ArrayType(b.context.Int64Type(), int(words)))
+	}
+	st.StructSetBody(elements, false)
+
+	return st
+}
+
+// payloadType returns the LLVM layout of the payload of a variant
+func (b *LLVMCodeBuilder) payloadType(t *Type, variant int) llvm.Type {
+	payload := t.Variants[variant].Payload
+	elements := make([]llvm.Type, 0, len(payload))
+	for _, p := range payload {
+		elements = append(elements, b.llvmType(p))
+	}
+	return b.context.StructType(elements, false)
+}
+
+// payloadPtr returns a pointer to the payload of a variant stored at ptr
+func (b *LLVMCodeBuilder) payloadPtr(t *Type, variant int, ptr llvm.Value) (llvm.Value, llvm.Type) {
+	payloadType := b.payloadType(t, variant)
+	words := b.builder.CreateStructGEP(b.llvmType(t), ptr, 1, "payload")
+	return b.builder.CreateBitCast(words, llvm.PointerType(payloadType, 0), t.Variants[variant].Name), payloadType
+}
+
+// loadPayload loads a field of the payload of a variant stored at ptr
+func (b *LLVMCodeBuilder) loadPayload(t *Type, variant int, ptr llvm.Value, field int) llvm.Value {
+	payload, payloadType := b.payloadPtr(t, variant, ptr)
+	fieldType := b.llvmType(t.Variants[variant].Payload[field])
+	return b.builder.CreateLoad(fieldType, b.builder.CreateStructGEP(payloadType, payload, field, ""), "")
+}
+
+// generateVariant constructs an enum value: Shape.Empty or Shape.Circle(r).
+// The type arguments of a generic enum come from the expected type or are
+// inferred from the payload.
+func (b *LLVMCodeBuilder) generateVariant(expr *syntax.SelectorExpr, enum *Type, args []syntax.Expr, want *Type) (value, error) {
+	// Payload values generated while inferring type arguments
+	var inferred []value
+	if enum.Generic != nil {
+		var err error
+		if want != nil && want.Origin == enum {
+			enum = want
+		} else if enum, inferred, err = b.inferVariant(expr, enum, args); err != nil {
+			return value{}, err
+		}
+	}
+
+	idx := enum.VariantIndex(expr.Sel)
+	if idx < 0 {
+		return value{}, b.errorAt(expr, "%s.%s undefined (enum %s has no variant %s)", exprName(expr.X), expr.Sel, enum, expr.Sel)
+	}
+	variant := enum.Variants[idx]
+	if len(args) != len(variant.Payload) {
+		return value{}, b.errorAt(expr, "variant %s.%s expects %d values, got %d", enum, variant.Name, len(variant.Payload), len(args))
+	}
+
+	payload := make([]llvm.Value, 0, len(args))
+	for i, arg := range args {
+		var v value
+		var err error
+		if inferred != nil {
+			v = inferred[i]
+		} else if v, err = b.generateExprAs(arg, variant.Payload[i]); err != nil {
+			return value{}, err
+		}
+		if v, err = b.assignable(v, variant.Payload[i], arg); err != nil {
+			return value{}, err
+		}
+		payload = append(payload, v.val)
+	}
+
+	return value{b.buildVariant(enum, idx, payload), enum}, nil
+}
+
+// buildVariant builds an enum value of the given variant in a temporary
+func (b *LLVMCodeBuilder) buildVariant(enum *Type, variant int, payload []llvm.Value) llvm.Value {
+	enumType := b.llvmType(enum)
+	tmp := b.createEntryAlloca(enumType, enum.Name)
+	tag := b.builder.CreateStructGEP(enumType, tmp, 0, "tag")
+	b.builder.CreateStore(llvm.ConstInt(b.context.Int32Type(), uint64(variant), false), tag)
+
+	if len(payload) > 0 {
+		ptr, payloadType := b.payloadPtr(enum, variant, tmp)
+		for i, v := range payload {
+			b.builder.CreateStore(v, b.builder.CreateStructGEP(payloadType, ptr, i, ""))
+		}
+	}
+
+	return b.builder.CreateLoad(enumType, tmp, enum.Name)
+}
+
+// inferVariant infers the type arguments of a generic enum from the payload
+// of a variant, returning the instantiation and the generated payload values
+func (b *LLVMCodeBuilder) inferVariant(expr *syntax.SelectorExpr, generic *Type, args []syntax.Expr) (*Type, []value, error) {
+	g := generic.Generic
+	variant := genericVariant(generic, expr.Sel)
+	if variant == nil {
+		return nil, nil, b.errorAt(expr, "%s.%s undefined (enum %s has no variant %s)", exprName(expr.X), expr.Sel, generic, expr.Sel)
+	}
+	if len(args) != len(variant.Types) {
+		return nil, nil, b.errorAt(expr, "variant %s.%s expects %d values, got %d", generic, variant.Name, len(variant.Types), len(args))
+	}
+
+	values, typeArgs, err := b.inferTypeArgs(generic.Name+"."+variant.Name, g.decl.TypeParams, g.scope, variant.Types, args, nil, nil, expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	enum, err := b.instantiateType(generic, typeArgs, expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enum, values, nil
+}
+
+// genericVariant returns the declaration of a variant of a generic enum, or
+// nil
+func genericVariant(generic *Type, name string) *syntax.Variant {
+	for i := range generic.Generic.decl.Enum.Variants {
+		if variant := &generic.Generic.decl.Enum.Variants[i]; variant.Name == name {
+			return variant
+		}
+	}
+	return nil
+}
+
+// isVariant reports whether name is a variant of the enum typ, which may be
+// generic
+func isVariant(typ *Type, name string) bool {
+	if typ.Kind != KindEnum {
+		return false
+	}
+	if typ.Generic != nil {
+		return genericVariant(typ, name) != nil
+	}
+	return typ.VariantIndex(name) >= 0
+}
+
+// generateMatchStmt switches on the tag of an enum value. Each arm binds the
+// payload of its variant to fresh locals; every variant must be covered by an
+// arm or by the else arm.
+func (b *LLVMCodeBuilder) generateMatchStmt(stmt *syntax.MatchStmt) error {
+	subject, err := b.generateExpr(stmt.X)
+	if err != nil {
+		return err
+	}
+	return b.generateMatch(stmt, subject)
+}
+
+// generateMatch lowers a match on an already generated subject
+func (b *LLVMCodeBuilder) generateMatch(stmt *syntax.MatchStmt, subject value) error {
+	enum := subject.typ
+	if enum.Kind != KindEnum {
+		return b.errorAt(stmt.X, "cannot match on non-enum type %s", enum)
+	}
+
+	// Check the arms before emitting any code
+	tags := make([]int, len(stmt.Arms))
+	covered := make(map[int]bool, len(stmt.Arms))
+	for i := range stmt.Arms {
+		arm := &stmt.Arms[i]
+		idx := enum.VariantIndex(arm.Variant)
+		if idx < 0 {
+			return b.errorAt(arm, "enum %s has no variant %s", enum, arm.Variant)
+		}
+		if covered[idx] {
+			return b.errorAt(arm, "duplicate match arm for variant %s", arm.Variant)
+		}
+		if n := len(enum.Variants[idx].Payload); len(arm.Bindings) != n {
+			return b.errorAt(arm, "variant %s holds %d values, pattern binds %d", arm.Variant, n, len(arm.Bindings))
+		}
+		covered[idx] = true
+		tags[i] = idx
+	}
+	if stmt.Else == nil {
+		var missing []string
+		for idx, variant := range enum.Variants {
+			if !covered[idx] {
+				missing = append(missing, variant.Name)
+			}
+		}
+		if len(missing) > 0 {
+			return b.errorAt(stmt, "match on %s is not exhaustive: missing %s", enum, strings.Join(missing, ", "))
+		}
+	}
+
+	enumType := b.llvmType(enum)
+	tmp := b.createEntryAlloca(enumType, "match")
+	b.builder.CreateStore(subject.val, tmp)
+	tagPtr := b.builder.CreateStructGEP(enumType, tmp, 0, "tag")
+	tag := b.builder.CreateLoad(b.context.Int32Type(), tagPtr, "tag")
+
+	exitBlock := b.context.AddBasicBlock(b.fn.value, "match.end")
+	var defaultBlock llvm.BasicBlock
+	if stmt.Else != nil {
+		defaultBlock = b.context.InsertBasicBlock(exitBlock, "match.else")
+	} else {
+		defaultBlock = b.context.InsertBasicBlock(exitBlock, "match.unreachable")
+	}
+	sw := b.builder.CreateSwitch(tag, defaultBlock, len(stmt.Arms))
+
+	for i := range stmt.Arms {
+		arm := &stmt.Arms[i]
+		armBlock := b.context.InsertBasicBlock(defaultBlock, "match."+arm.Variant)
+		sw.AddCase(llvm.ConstInt(b.context.Int32Type(), uint64(tags[i]), false), armBlock)
+		b.builder.SetInsertPointAtEnd(armBlock)
+		if err := b.generateMatchArm(arm, enum, tags[i], tmp); err != nil {
+			return err
+		}
+		if !b.isTerminated() {
+			b.builder.CreateBr(exitBlock)
+		}
+	}
+
+	b.builder.SetInsertPointAtEnd(defaultBlock)
+	if stmt.Else != nil {
+		if err := b.generateScopedBlock(stmt.Else); err != nil {
+			return err
+		}
+		if !b.isTerminated() {
+			b.builder.CreateBr(exitBlock)
+		}
+	} else {
+		b.builder.CreateUnreachable()
+	}
+
+	b.builder.SetInsertPointAtEnd(exitBlock)
+	return nil
+}
+
+// generateMatchArm copies the payload of the matched variant into locals
+// named by the arm bindings and generates the arm body
+func (b *LLVMCodeBuilder) generateMatchArm(arm *syntax.MatchArm, enum *Type, tag int, subject llvm.Value) error {
+	b.pushScope()
+	defer b.popScope()
+
+	if len(arm.Bindings) > 0 {
+		payload, payloadType := b.payloadPtr(enum, tag, subject)
+		for i, name := range arm.Bindings {
+			if b.fn.scope.lookupLocal(name) != nil {
+				return b.errorAt(arm, "%s bound more than once in pattern", name)
+			}
+			typ := enum.Variants[tag].Payload[i]
+			fieldType := b.llvmType(typ)
+			field := b.builder.CreateLoad(fieldType, b.builder.CreateStructGEP(payloadType, payload, i, ""), name)
+			ptr := b.createLocal(typ, name)
+			b.builder.CreateStore(field, ptr)
+			b.fn.scope.define(&symbol{kind: symbolVar, name: name, typ: typ, ptr: ptr, decl: arm})
+		}
+	}
+
+	return b.generateBlock(arm.Body)
+}
@@ -1,121 +1,669 @@
 package codegen
 
 import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
 	"jmpeax.com/guayavita/gvc/internal/syntax"
 	"tinygo.org/x/go-llvm"
 )
 
+// value pairs a generated LLVM value with its Guayavita type
+type value struct {
+	val llvm.Value
+	typ *Type
+}
+
 // generateExpr generates LLVM IR for an expression
-func (b *LLVMCodeBuilder) generateExpr(expr syntax.Expr) (llvm.Value, error) {
+func (b *LLVMCodeBuilder) generateExpr(expr syntax.Expr) (value, error) {
+	return b.generateExprAs(expr, nil)
+}
+
+// generateExprAs generates LLVM IR for an expression whose expected type is
+// want. The expected type only guides untyped literals and the instantiation
+// of generic types; callers still check the resulting type with assignable.
+func (b *LLVMCodeBuilder) generateExprAs(expr syntax.Expr, want *Type) (value, error) {
+	// A T is wrapped where a T? is expected, so only none needs the optional
+	if want != nil && want.Kind == KindOptional && !isNone(expr) {
+		want = want.Elem
+	}
+
 	switch e := expr.(type) {
 	case *syntax.BasicLit:
-		return b.generateBasicLit(e)
+		return b.generateBasicLit(e, want)
+	case *syntax.Ident:
+		return b.generateIdent(e)
+	case *syntax.SelectorExpr:
+		return b.generateSelectorExpr(e, want)
 	case *syntax.BinaryExpr:
-		return b.generateBinaryExpr(e)
+		return b.generateBinaryExpr(e, want)
+	case *syntax.UnaryExpr:
+		return b.generateUnaryExpr(e, want)
+	case *syntax.CastExpr:
+		return b.generateCastExpr(e)
 	case *syntax.CallExpr:
-		return b.generateCallExpr(e)
+		return b.generateCallExpr(e, want)
+	case *syntax.StructLit:
+		return b.generateStructLit(e, want)
+	case *syntax.TryExpr:
+		return b.generateTryExpr(e)
+	case *syntax.IndexExpr:
+		return b.generateIndexExpr(e)
+	case *syntax.TupleExpr:
+		return b.generateTupleExpr(e, want)
+	case *syntax.InterpolatedString:
+		return b.generateInterpolatedString(e)
+	case *syntax.ArrayLit:
+		return b.generateArrayLit(e, want)
+	case *syntax.FuncLit:
+		return b.generateFuncLit(e)
+	case *syntax.RangeExpr:
+		return value{}, b.errorAt(e, "range can only be iterated by for-in or used as slice bounds")
 	default:
-		return llvm.Value{}, b.errorAt(e, "unsupported expression type: %T", expr)
+		return value{}, b.errorAt(e, "unsupported expression type: %T", expr)
+	}
+}
+
+// assignable checks that v can be used where a value of type want is expected
+func (b *LLVMCodeBuilder) assignable(v value, want *Type, node syntax.Node) (value, error) {
+	if v.typ == want {
+		return v, nil
+	}
+	if want.Kind == KindOptional && v.typ == want.Elem {
+		return value{b.wrapOptional(v.val, want), want}, nil
+	}
+	// Values are boxed where an interface they implement is expected
+	if want.Kind == KindInterface && implements(v.typ, want) {
+		return b.toInterface(v, want), nil
+	}
+	if want.Kind == KindOptional && want.Elem.Kind == KindInterface && implements(v.typ, want.Elem) {
+		return value{b.wrapOptional(b.toInterface(v, want.Elem).val, want), want}, nil
+	}
+	if expr, ok := node.(syntax.Expr); ok && v.typ.Kind == KindOptional && v.typ.Elem == want {
+		return value{}, b.optionalUseError(expr, v.typ)
+	}
+	if want.Kind == KindInterface {
+		return value{}, b.errorAt(node, "cannot use value of type %s as %s (%s does not implement %s)", v.typ, want, v.typ, want)
+	}
+	return value{}, b.errorAt(node, "cannot use value of type %s as %s", v.typ, want)
+}
+
+// isUntypedLiteral reports whether expr is a character literal or a numeric
+// literal without type suffix, whose type is taken from its context
+func isUntypedLiteral(expr syntax.Expr) bool {
+	switch e := expr.(type) {
+	case *syntax.BasicLit:
+		if e.Kind == "CHAR" {
+			return true
+		}
+		if e.Kind != "INT" && e.Kind != "FLOAT" {
+			return false
+		}
+		_, _, suffix := syntax.SplitNumber(e.Value)
+		return suffix == ""
+	case *syntax.UnaryExpr:
+		return (e.Op == "-" || e.Op == "+" || e.Op == "~") && isUntypedLiteral(e.X)
+	default:
+		return false
 	}
 }
 
 // generateBasicLit generates LLVM IR for a basic literal
-func (b *LLVMCodeBuilder) generateBasicLit(lit *syntax.BasicLit) (llvm.Value, error) {
+func (b *LLVMCodeBuilder) generateBasicLit(lit *syntax.BasicLit, want *Type) (value, error) {
 	switch lit.Kind {
-	case "INT":
-		// Parse integer value and create constant
-		return llvm.ConstInt(b.context.Int32Type(), 42, false), nil // Simplified for now
+	case "INT", "FLOAT":
+		return b.generateNumber(lit, want)
+	case "CHAR":
+		return b.generateChar(lit, want)
 	case "STRING":
 		// Create string constant
 		str := b.builder.CreateGlobalStringPtr(lit.Value, "str")
-		return str, nil
+		return value{str, typeString}, nil
+	case "NONE":
+		return b.generateNone(lit, want)
+	case "BOOL":
+		var v uint64
+		if lit.Value == "true" {
+			v = 1
+		}
+		return value{llvm.ConstInt(b.context.Int1Type(), v, false), typeBool}, nil
 	default:
-		return llvm.Value{}, b.errorAt(lit, "unsupported literal kind: %s", lit.Kind)
+		return value{}, b.errorAt(lit, "unsupported literal kind: %s", lit.Kind)
 	}
 }
 
-// generateBinaryExpr generates LLVM IR for a binary expression
-func (b *LLVMCodeBuilder) generateBinaryExpr(expr *syntax.BinaryExpr) (llvm.Value, error) {
-	left, err := b.generateExpr(expr.Left)
+// generateNumber generates a numeric literal. A suffix gives the type of the
+// literal; otherwise an integer takes the expected numeric type and a float
+// the expected float type, defaulting to i32 and f64. The value must be
+// representable in that type.
+func (b *LLVMCodeBuilder) generateNumber(lit *syntax.BasicLit, want *Type) (value, error) {
+	text, neg := strings.CutPrefix(lit.Value, "-")
+	digits, base, suffix := syntax.SplitNumber(text)
+	typ := typeI32
+	if lit.Kind == "FLOAT" {
+		typ = typeF64
+	}
+	switch {
+	case suffix != "":
+		typ = primitiveTypes[suffix]
+	case want != nil && (want.Kind == KindFloat || want.Kind == KindInt && lit.Kind == "INT"):
+		typ = want
+	}
+
+	if typ.Kind == KindFloat {
+		var f float64
+		var err error
+		if base == 10 {
+			f, err = strconv.ParseFloat(digits, 64)
+		} else {
+			var n uint64
+			n, err = strconv.ParseUint(digits, base, 64)
+			f = float64(n)
+		}
+		if err != nil || typ.Bits == 32 && f > math.MaxFloat32 {
+			return value{}, b.errorAt(lit, "float literal %s overflows %s", lit.Value, typ)
+		}
+		if neg {
+			f = -f
+		}
+		return value{llvm.ConstFloat(b.llvmType(typ), f), typ}, nil
+	}
+
+	n, err := strconv.ParseUint(digits, base, 64)
+	limit := uint64(math.MaxUint64) >> (64 - typ.Bits)
+	switch {
+	case typ.Signed && neg:
+		limit = limit/2 + 1
+	case typ.Signed:
+		limit /= 2
+	case neg:
+		limit = 0
+	}
+	if err != nil || n > limit {
+		return value{}, b.errorAt(lit, "integer literal %s overflows %s", lit.Value, typ)
+	}
+	if neg {
+		n = -n
+	}
+	return value{llvm.ConstInt(b.llvmType(typ), n, false), typ}, nil
+}
+
+// generateChar generates a character literal as its code point, a rune, or
+// as the expected integer type when the code point fits it, as in c == 'a'
+// for a byte c
+func (b *LLVMCodeBuilder) generateChar(lit *syntax.BasicLit, want *Type) (value, error) {
+	r, _ := utf8.DecodeRuneInString(lit.Value)
+	typ := typeRune
+	if want != nil && want.Kind == KindInt {
+		typ = want
+	}
+	limit := uint64(math.MaxUint64) >> (64 - typ.Bits)
+	if typ.Signed {
+		limit /= 2
+	}
+	if uint64(r) > limit {
+		return value{}, b.errorAt(lit, "character literal %s overflows %s", strconv.QuoteRune(r), typ)
+	}
+	return value{llvm.ConstInt(b.llvmType(typ), uint64(r), false), typ}, nil
+}
+
+// generateIdent loads the value of a variable
+func (b *LLVMCodeBuilder) generateIdent(ident *syntax.Ident) (value, error) {
+	sym := b.fn.scope.lookup(ident.Name)
+	if sym == nil {
+		return value{}, b.errorAt(ident, "undefined: %s", ident.Name)
+	}
+	return b.loadSymbol(sym, ident)
+}
+
+// generateSelectorExpr generates LLVM IR for a qualified pkg.name reference,
+// an enum variant without payload, or a struct field access
+func (b *LLVMCodeBuilder) generateSelectorExpr(expr *syntax.SelectorExpr, want *Type) (value, error) {
+	sym, ok, err := b.lookupQualified(expr)
 	if err != nil {
-		return llvm.Value{}, err
+		return value{}, err
+	}
+	if !ok {
+		typ, err := b.lookupNamedType(expr.X)
+		if err != nil {
+			return value{}, err
+		}
+		if typ != nil && typ.Kind == KindEnum {
+			return b.generateVariant(expr, typ, nil, want)
+		}
+		if typ != nil {
+			return value{}, b.errorAt(expr, "%s.%s is not an expression", typ, expr.Sel)
+		}
+		return b.generateFieldAccess(expr)
+	}
+	return b.loadSymbol(sym, expr)
+}
+
+// loadSymbol produces the value a symbol denotes when used as an expression
+func (b *LLVMCodeBuilder) loadSymbol(sym *symbol, node syntax.Node) (value, error) {
+	switch sym.kind {
+	case symbolVar:
+		return value{b.builder.CreateLoad(b.llvmType(sym.typ), sym.ptr, sym.name), sym.typ}, nil
+	case symbolFunc:
+		return b.functionValue(sym.fn, node)
+	case symbolType:
+		return value{}, b.errorAt(node, "type %s is not an expression", sym.name)
+	default:
+		return value{}, b.errorAt(node, "use of package %s without selector", sym.name)
+	}
+}
+
+// generateUnaryExpr generates LLVM IR for a unary expression
+func (b *LLVMCodeBuilder) generateUnaryExpr(expr *syntax.UnaryExpr, want *Type) (value, error) {
+	// Negative literals are folded so that e.g. -128 fits an i8
+	if lit, ok := expr.X.(*syntax.BasicLit); ok && expr.Op == "-" && (lit.Kind == "INT" || lit.Kind == "FLOAT") {
+		return b.generateBasicLit(&syntax.BasicLit{Kind: lit.Kind, Value: "-" + lit.Value, Pos_: lit.Pos_}, want)
 	}
 
-	right, err := b.generateExpr(expr.Right)
+	operand, err := b.generateExprAs(expr.X, want)
 	if err != nil {
-		return llvm.Value{}, err
+		return value{}, err
 	}
 
 	switch expr.Op {
 	case "+":
-		return b.builder.CreateAdd(left, right, "add"), nil
+		if !operand.typ.IsNumeric() {
+			return value{}, b.errorAt(expr, "invalid operation: +%s", operand.typ)
+		}
+		return operand, nil
 	case "-":
-		return b.builder.CreateSub(left, right, "sub"), nil
-	case "*":
-		return b.builder.CreateMul(left, right, "mul"), nil
-	case "/":
-		return b.builder.CreateSDiv(left, right, "div"), nil
+		switch operand.typ.Kind {
+		case KindInt:
+			return value{b.builder.CreateNeg(operand.val, "neg"), operand.typ}, nil
+		case KindFloat:
+			return value{b.builder.CreateFNeg(operand.val, "fneg"), operand.typ}, nil
+		}
+	case "!":
+		if operand.typ == typeBool {
+			return value{b.builder.CreateNot(operand.val, "not"), typeBool}, nil
+		}
+	case "~":
+		if operand.typ.Kind == KindInt {
+			return value{b.builder.CreateNot(operand.val, "bitnot"), operand.typ}, nil
+		}
+	}
+	return value{}, b.errorAt(expr, "invalid operation: %s%s", expr.Op, operand.typ)
+}
+
+// generateBinaryExpr generates LLVM IR for a binary expression
+func (b *LLVMCodeBuilder) generateBinaryExpr(expr *syntax.BinaryExpr, want *Type) (value, error) {
+	if expr.Op == "&&" || expr.Op == "||" {
+		return b.generateLogicalExpr(expr)
+	}
+	if isComparison(expr.Op) && (isNone(expr.Left) || isNone(expr.Right)) {
+		return b.generateNoneComparison(expr)
+	}
+	if isShift(expr.Op) {
+		return b.generateShiftExpr(expr, want)
+	}
+
+	// Arithmetic results take the expected type; comparisons do not
+	hint := want
+	if isComparison(expr.Op) {
+		hint = nil
+	}
+
+	// An untyped literal operand takes its type from the other operand
+	var left, right value
+	var err error
+	if isUntypedLiteral(expr.Left) && !isUntypedLiteral(expr.Right) {
+		if right, err = b.generateExprAs(expr.Right, hint); err != nil {
+			return value{}, err
+		}
+		if left, err = b.generateExprAs(expr.Left, right.typ); err != nil {
+			return value{}, err
+		}
+	} else {
+		if left, err = b.generateExprAs(expr.Left, hint); err != nil {
+			return value{}, err
+		}
+		if right, err = b.generateExprAs(expr.Right, left.typ); err != nil {
+			return value{}, err
+		}
+	}
+
+	if left.typ.Kind == KindOptional {
+		return value{}, b.optionalUseError(expr.Left, left.typ)
+	}
+	if right.typ.Kind == KindOptional {
+		return value{}, b.optionalUseError(expr.Right, right.typ)
+	}
+	if left.typ != right.typ {
+		return value{}, b.errorAt(expr, "mismatched types %s and %s for operator %s", left.typ, right.typ, expr.Op)
+	}
+
+	if isComparison(expr.Op) {
+		return b.generateComparison(expr, left, right)
+	}
+	return b.generateArithmetic(expr, left, right)
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
 	default:
-		return llvm.Value{}, b.errorAt(expr, "unsupported binary operator: %s", expr.Op)
+		return false
 	}
 }
 
-// generateCallExpr generates LLVM IR for a function call
-func (b *LLVMCodeBuilder) generateCallExpr(expr *syntax.CallExpr) (llvm.Value, error) {
-	// Get function name from identifier
-	ident, ok := expr.Fun.(*syntax.Ident)
-	if !ok {
-		return llvm.Value{}, b.errorAt(expr, "unsupported function call expression: %T", expr.Fun)
+func isShift(op string) bool {
+	return op == "<<" || op == ">>"
+}
+
+// generateArithmetic lowers + - * / % on operands of the same numeric type,
+// and the bitwise operators & | ^ on integers and bools.
+func (b *LLVMCodeBuilder) generateArithmetic(expr *syntax.BinaryExpr, left, right value) (value, error) {
+	typ := left.typ
+	switch typ.Kind {
+	case KindBool:
+		switch expr.Op {
+		case "&":
+			return value{b.builder.CreateAnd(left.val, right.val, "and"), typ}, nil
+		case "|":
+			return value{b.builder.CreateOr(left.val, right.val, "or"), typ}, nil
+		case "^":
+			return value{b.builder.CreateXor(left.val, right.val, "xor"), typ}, nil
+		}
+	case KindInt:
+		switch expr.Op {
+		case "&":
+			return value{b.builder.CreateAnd(left.val, right.val, "and"), typ}, nil
+		case "|":
+			return value{b.builder.CreateOr(left.val, right.val, "or"), typ}, nil
+		case "^":
+			return value{b.builder.CreateXor(left.val, right.val, "xor"), typ}, nil
+		}
+		switch expr.Op {
+		case "+":
+			return value{b.builder.CreateAdd(left.val, right.val, "add"), typ}, nil
+		case "-":
+			return value{b.builder.CreateSub(left.val, right.val, "sub"), typ}, nil
+		case "*":
+			return value{b.builder.CreateMul(left.val, right.val, "mul"), typ}, nil
+		case "/":
+			if typ.Signed {
+				return value{b.builder.CreateSDiv(left.val, right.val, "div"), typ}, nil
+			}
+			return value{b.builder.CreateUDiv(left.val, right.val, "div"), typ}, nil
+		case "%":
+			if typ.Signed {
+				return value{b.builder.CreateSRem(left.val, right.val, "rem"), typ}, nil
+			}
+			return value{b.builder.CreateURem(left.val, right.val, "rem"), typ}, nil
+		}
+	case KindFloat:
+		switch expr.Op {
+		case "+":
+			return value{b.builder.CreateFAdd(left.val, right.val, "fadd"), typ}, nil
+		case "-":
+			return value{b.builder.CreateFSub(left.val, right.val, "fsub"), typ}, nil
+		case "*":
+			return value{b.builder.CreateFMul(left.val, right.val, "fmul"), typ}, nil
+		case "/":
+			return value{b.builder.CreateFDiv(left.val, right.val, "fdiv"), typ}, nil
+		case "%":
+			return value{b.builder.CreateFRem(left.val, right.val, "frem"), typ}, nil
+		}
 	}
+	return value{}, b.errorAt(expr, "unsupported binary operator %s for type %s", expr.Op, typ)
+}
 
-	funcName := ident.Name
+// generateShiftExpr generates x << n and x >> n. The result has the type of
+// x, which an untyped literal x takes from the context rather than from n.
+func (b *LLVMCodeBuilder) generateShiftExpr(expr *syntax.BinaryExpr, want *Type) (value, error) {
+	left, err := b.generateExprAs(expr.Left, want)
+	if err != nil {
+		return value{}, err
+	}
+	right, err := b.generateExprAs(expr.Right, left.typ)
+	if err != nil {
+		return value{}, err
+	}
+	if left.typ.Kind == KindOptional {
+		return value{}, b.optionalUseError(expr.Left, left.typ)
+	}
+	if right.typ.Kind == KindOptional {
+		return value{}, b.optionalUseError(expr.Right, right.typ)
+	}
+	if left.typ.Kind != KindInt {
+		return value{}, b.errorAt(expr, "unsupported binary operator %s for type %s", expr.Op, left.typ)
+	}
+	return b.generateShift(expr, left, right)
+}
 
-	// Handle print function specially
-	if funcName == "print" {
-		return b.generatePrintCall(expr)
+// generateShift shifts an integer by a count of any integer type. Right
+// shifts are arithmetic for signed types and logical for unsigned ones.
+// Unlike in LLVM, counts of at least the width of the type are defined:
+// every bit is shifted out, leaving 0, or -1 for negative values shifted
+// right. Negative counts are treated as such large counts.
+func (b *LLVMCodeBuilder) generateShift(expr *syntax.BinaryExpr, x, count value) (value, error) {
+	if count.typ.Kind != KindInt {
+		return value{}, b.errorAt(expr.Right, "shift count must be an integer, got %s", count.typ)
 	}
 
-	// For other functions, try to find them in the module
-	function := b.module.NamedFunction(funcName)
-	if function.IsNil() {
-		return llvm.Value{}, b.errorAt(expr, "undefined function: %s", funcName)
+	typ := x.typ
+	llvmType := b.llvmType(typ)
+	width := llvm.ConstInt(b.llvmType(count.typ), uint64(typ.Bits), false)
+	over := b.builder.CreateICmp(llvm.IntUGE, count.val, width, "over")
+
+	n := count.val
+	switch {
+	case count.typ.Bits > typ.Bits:
+		n = b.builder.CreateTrunc(n, llvmType, "count")
+	case count.typ.Bits < typ.Bits:
+		n = b.builder.CreateZExt(n, llvmType, "count")
 	}
 
-	// Generate arguments
-	var args []llvm.Value
-	for _, arg := range expr.Args {
-		argValue, err := b.generateExpr(arg)
+	zero := llvm.ConstInt(llvmType, 0, false)
+	switch {
+	case expr.Op == "<<":
+		shifted := b.builder.CreateShl(x.val, n, "shl")
+		return value{b.builder.CreateSelect(over, zero, shifted, "shl"), typ}, nil
+	case typ.Signed:
+		// Shifting by width-1 spreads the sign bit over the whole value
+		n = b.builder.CreateSelect(over, llvm.ConstInt(llvmType, uint64(typ.Bits-1), false), n, "count")
+		return value{b.builder.CreateAShr(x.val, n, "ashr"), typ}, nil
+	default:
+		shifted := b.builder.CreateLShr(x.val, n, "lshr")
+		return value{b.builder.CreateSelect(over, zero, shifted, "lshr"), typ}, nil
+	}
+}
+
+var (
+	signedPredicates = map[string]llvm.IntPredicate{
+		"==": llvm.IntEQ, "!=": llvm.IntNE,
+		"<": llvm.IntSLT, "<=": llvm.IntSLE, ">": llvm.IntSGT, ">=": llvm.IntSGE,
+	}
+	unsignedPredicates = map[string]llvm.IntPredicate{
+		"==": llvm.IntEQ, "!=": llvm.IntNE,
+		"<": llvm.IntULT, "<=": llvm.IntULE, ">": llvm.IntUGT, ">=": llvm.IntUGE,
+	}
+	floatPredicates = map[string]llvm.FloatPredicate{
+		"==": llvm.FloatOEQ, "!=": llvm.FloatUNE,
+		"<": llvm.FloatOLT, "<=": llvm.FloatOLE, ">": llvm.FloatOGT, ">=": llvm.FloatOGE,
+	}
+)
+
+// generateComparison lowers comparison operators to icmp/fcmp
+func (b *LLVMCodeBuilder) generateComparison(expr *syntax.BinaryExpr, left, right value) (value, error) {
+	switch left.typ.Kind {
+	case KindInt:
+		preds := unsignedPredicates
+		if left.typ.Signed {
+			preds = signedPredicates
+		}
+		return value{b.builder.CreateICmp(preds[expr.Op], left.val, right.val, "cmp"), typeBool}, nil
+	case KindFloat:
+		return value{b.builder.CreateFCmp(floatPredicates[expr.Op], left.val, right.val, "fcmp"), typeBool}, nil
+	case KindBool:
+		if expr.Op == "==" || expr.Op == "!=" {
+			return value{b.builder.CreateICmp(signedPredicates[expr.Op], left.val, right.val, "cmp"), typeBool}, nil
+		}
+	case KindString:
+		// Strings compare by content
+		strcmp, err := b.declareExternalFunction("strcmp")
 		if err != nil {
-			return llvm.Value{}, b.errorAt(expr, "failed to generate argument: %v", err)
+			return value{}, b.errorAt(expr, "%v", err)
 		}
-		args = append(args, argValue)
+		diff := b.builder.CreateCall(strcmp.GlobalValueType(), strcmp, []llvm.Value{left.val, right.val}, "strcmp")
+		zero := llvm.ConstInt(b.context.Int32Type(), 0, false)
+		return value{b.builder.CreateICmp(signedPredicates[expr.Op], diff, zero, "cmp"), typeBool}, nil
+	}
+	return value{}, b.errorAt(expr, "unsupported comparison %s for type %s", expr.Op, left.typ)
+}
+
+// generateLogicalExpr lowers && and || with short-circuit evaluation
+func (b *LLVMCodeBuilder) generateLogicalExpr(expr *syntax.BinaryExpr) (value, error) {
+	left, err := b.generateCondition(expr.Left)
+	if err != nil {
+		return value{}, err
+	}
+	leftBlock := b.builder.GetInsertBlock()
+
+	rhsBlock := b.context.AddBasicBlock(b.fn.value, "logic.rhs")
+	endBlock := b.context.AddBasicBlock(b.fn.value, "logic.end")
+	if expr.Op == "&&" {
+		b.builder.CreateCondBr(left.val, rhsBlock, endBlock)
+	} else {
+		b.builder.CreateCondBr(left.val, endBlock, rhsBlock)
 	}
 
-	// Call the function
-	return b.builder.CreateCall(function.GlobalValueType(), function, args, "call"), nil
+	// The right operand only runs when the left one is true for && and false
+	// for ||, which may narrow optionals
+	b.builder.SetInsertPointAtEnd(rhsBlock)
+	b.pushScope()
+	if then, otherwise := noneChecks(expr.Left); expr.Op == "&&" {
+		b.narrow(then)
+	} else {
+		b.narrow(otherwise)
+	}
+	right, err := b.generateCondition(expr.Right)
+	b.popScope()
+	if err != nil {
+		return value{}, err
+	}
+	rightBlock := b.builder.GetInsertBlock()
+	b.builder.CreateBr(endBlock)
+
+	b.builder.SetInsertPointAtEnd(endBlock)
+	phi := b.builder.CreatePHI(b.context.Int1Type(), "logic")
+	phi.AddIncoming([]llvm.Value{left.val, right.val}, []llvm.BasicBlock{leftBlock, rightBlock})
+	return value{phi, typeBool}, nil
+}
+
+// generateCallExpr generates LLVM IR for a function call
+func (b *LLVMCodeBuilder) generateCallExpr(expr *syntax.CallExpr, want *Type) (value, error) {
+	var sym *symbol
+	switch callee := expr.Fun.(type) {
+	case *syntax.Ident:
+		sym = b.fn.scope.lookup(callee.Name)
+		// Handle print function specially unless shadowed
+		if sym == nil && callee.Name == "print" {
+			return b.generatePrintCall(expr)
+		}
+		if sym == nil && callee.Name == "len" {
+			return b.generateLenCall(expr)
+		}
+		if sym == nil && callee.Name == "append" {
+			return b.generateAppendCall(expr, want)
+		}
+		if sym == nil && (callee.Name == resultOk || callee.Name == resultErr) {
+			return b.generateResultCall(expr, callee, want)
+		}
+		if sym == nil {
+			return value{}, b.errorAt(expr, "undefined function: %s", callee.Name)
+		}
+	case *syntax.SelectorExpr:
+		qualified, ok, err := b.lookupQualified(callee)
+		if err != nil {
+			return value{}, err
+		}
+		if !ok {
+			typ, err := b.lookupNamedType(callee.X)
+			if err != nil {
+				return value{}, err
+			}
+			if typ != nil {
+				return b.generateTypeCall(expr, callee, typ, want)
+			}
+			return b.generateMethodCall(expr, callee)
+		}
+		sym = qualified
+	default:
+		closure, err := b.generateExpr(expr.Fun)
+		if err != nil {
+			return value{}, err
+		}
+		return b.generateClosureCall(expr, closure)
+	}
+
+	if sym.kind == symbolVar {
+		closure, err := b.loadSymbol(sym, expr.Fun)
+		if err != nil {
+			return value{}, err
+		}
+		return b.generateClosureCall(expr, closure)
+	}
+	if sym.kind != symbolFunc {
+		return value{}, b.errorAt(expr, "cannot call non-function %s", sym.name)
+	}
+	if sym.fn.isGeneric() {
+		return b.generateGenericCall(expr, sym.fn, want)
+	}
+	return b.generateDirectCall(expr, sym.fn, llvm.Value{})
+}
+
+// generateDirectCall emits a call to a known function, checking the arguments
+// against its parameter types. recv points to the receiver of methods taking
+// self and is ignored otherwise.
+func (b *LLVMCodeBuilder) generateDirectCall(expr *syntax.CallExpr, fn *function, recv llvm.Value) (value, error) {
+	if len(expr.Args) != len(fn.params) {
+		return value{}, b.errorAt(expr, "function %s expects %d arguments, got %d", fn.name, len(fn.params), len(expr.Args))
+	}
+
+	args := make([]value, 0, len(expr.Args))
+	for i, arg := range expr.Args {
+		argValue, err := b.generateExprAs(arg, fn.params[i])
+		if err != nil {
+			return value{}, err
+		}
+		args = append(args, argValue)
+	}
+	return b.emitCall(expr, fn, recv, args)
 }
 
 // generatePrintCall generates LLVM IR for a print function call
-func (b *LLVMCodeBuilder) generatePrintCall(expr *syntax.CallExpr) (llvm.Value, error) {
+func (b *LLVMCodeBuilder) generatePrintCall(expr *syntax.CallExpr) (value, error) {
 	if len(expr.Args) != 1 {
-		return llvm.Value{}, b.errorAt(expr, "print function expects exactly 1 argument, got %d", len(expr.Args))
+		return value{}, b.errorAt(expr, "print function expects exactly 1 argument, got %d", len(expr.Args))
 	}
 
 	// Generate the string argument
-	arg, err := b.generateExpr(expr.Args[0])
+	arg, err := b.generateExprAs(expr.Args[0], typeString)
 	if err != nil {
-		return llvm.Value{}, b.errorAt(expr, "failed to generate print argument: %v", err)
+		return value{}, err
+	}
+	if arg.typ != typeString {
+		return value{}, b.errorAt(expr.Args[0], "print expects a string, got %s", arg.typ)
 	}
 
 	// Get the print function
 	printFunc := b.module.NamedFunction("print")
 	if printFunc.IsNil() {
-		return llvm.Value{}, b.errorAt(expr, "print function not found - external functions not initialized")
+		return value{}, b.errorAt(expr, "print function not found - external functions not initialized")
 	}
 
 	// Call print function
-	b.builder.CreateCall(printFunc.GlobalValueType(), printFunc, []llvm.Value{arg}, "")
+	b.builder.CreateCall(printFunc.GlobalValueType(), printFunc, []llvm.Value{arg.val}, "")
 
-	// Print returns void, but we need to return something for expressions
-	return llvm.ConstInt(b.context.Int32Type(), 0, false), nil
+	// Print returns void
+	return value{llvm.Value{}, typeNone}, nil
 }
@@ -53,6 +53,25 @@ func (b *LLVMCodeBuilder) initializeExternalFunctions() {
 
 	// Register puts from libc as alternative
 	b.externals.RegisterFunction("puts", b.context.Int32Type(), []llvm.Type{i8PtrType}, false)
+
+	// Register strcmp from libc for string comparison
+	b.externals.RegisterFunction("strcmp", b.context.Int32Type(), []llvm.Type{i8PtrType, i8PtrType}, false)
+
+	// Register string and memory helpers from libc for slicing
+	i64Type := b.context.Int64Type()
+	b.externals.RegisterFunction("strlen", i64Type, []llvm.Type{i8PtrType}, false)
+	b.externals.RegisterFunction("malloc", i8PtrType, []llvm.Type{i64Type}, false)
+	b.externals.RegisterFunction("memcpy", i8PtrType, []llvm.Type{i8PtrType, i8PtrType, i64Type}, false)
+
+	// Register realloc from libc to grow slices
+	b.externals.RegisterFunction("realloc", i8PtrType, []llvm.Type{i8PtrType, i64Type}, false)
+
+	// Register snprintf from libc to format interpolated strings
+	b.externals.RegisterFunction("snprintf", b.context.Int32Type(), []llvm.Type{i8PtrType, i64Type}, true)
+
+	// Register dprintf and abort from libc for runtime errors
+	b.externals.RegisterFunction("dprintf", b.context.Int32Type(), []llvm.Type{b.context.Int32Type(), i8PtrType}, true)
+	b.externals.RegisterFunction("abort", b.context.VoidType(), nil, false)
 }
 
 // declareExternalFunction declares an external function in the LLVM module
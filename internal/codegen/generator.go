@@ -8,25 +8,32 @@ import (
 	"tinygo.org/x/go-llvm"
 )
 
-// generateIR generates LLVM IR from the AST
-func (b *LLVMCodeBuilder) generateIR(ast *syntax.File) error {
-	// Create main function as entry point
-	mainType := llvm.FunctionType(b.context.Int32Type(), []llvm.Type{}, false)
-	mainFunc := llvm.AddFunction(b.module, "main", mainType)
-
-	entry := b.context.AddBasicBlock(mainFunc, "entry")
-	b.builder.SetInsertPoint(entry, entry.FirstInstruction())
+// generateIR generates LLVM IR for every package of the program. Packages
+// arrive dependencies first, so imported symbols are always declared before
+// the packages that use them.
+func (b *LLVMCodeBuilder) generateIR(pkgs []*syntax.Package) error {
+	b.packages = make(map[string]*packageScope)
+	b.declareUniverse()
 
-	// Generate code for each declaration
-	for _, decl := range ast.Decls {
-
-		if err := b.generateDecl(decl); err != nil {
+	var scopes []*packageScope
+	for _, pkg := range pkgs {
+		ps, err := b.declarePackage(pkg)
+		if err != nil {
+			return err
+		}
+		if err := b.generatePackage(ps); err != nil {
 			return err
 		}
+		scopes = append(scopes, ps)
 	}
 
-	// Return 0 from main
-	b.builder.CreateRet(llvm.ConstInt(b.context.Int32Type(), 0, false))
+	if err := b.generateInstances(); err != nil {
+		return err
+	}
+
+	if err := b.generateEntryPoint(scopes); err != nil {
+		return err
+	}
 
 	// Verify the module
 	if err := llvm.VerifyModule(b.module, llvm.ReturnStatusAction); err != nil {
@@ -38,6 +45,47 @@ func (b *LLVMCodeBuilder) generateIR(ast *syntax.File) error {
 	return nil
 }
 
+// generateEntryPoint emits the C main function: it runs the initializers of
+// every package in dependency order and then calls main of the entry package
+func (b *LLVMCodeBuilder) generateEntryPoint(scopes []*packageScope) error {
+	mainType := llvm.FunctionType(b.context.Int32Type(), []llvm.Type{}, false)
+	mainFunc := llvm.AddFunction(b.module, "main", mainType)
+
+	entry := b.context.AddBasicBlock(mainFunc, "entry")
+	b.builder.SetInsertPointAtEnd(entry)
+
+	for _, ps := range scopes {
+		b.builder.CreateCall(ps.init.GlobalValueType(), ps.init, []llvm.Value{}, "")
+	}
+
+	if len(scopes) == 0 {
+		b.builder.CreateRet(llvm.ConstInt(b.context.Int32Type(), 0, false))
+		return nil
+	}
+
+	sym := scopes[len(scopes)-1].scope.lookupLocal("main")
+	if sym == nil || sym.kind != symbolFunc {
+		// Nothing to run, e.g. when only emitting IR for a library package
+		b.builder.CreateRet(llvm.ConstInt(b.context.Int32Type(), 0, false))
+		return nil
+	}
+
+	fn := sym.fn
+	if len(fn.params) != 0 {
+		return b.errorAt(fn.decl, "fun main must have no parameters")
+	}
+
+	result := b.builder.CreateCall(fn.fnType, fn.value, []llvm.Value{}, "")
+	if fn.result.Kind == KindInt {
+		// An integer result of main becomes the process exit status
+		b.builder.CreateRet(b.builder.CreateIntCast(result, b.context.Int32Type(), "status"))
+	} else {
+		b.builder.CreateRet(llvm.ConstInt(b.context.Int32Type(), 0, false))
+	}
+
+	return nil
+}
+
 func SupportedTriples() []string {
 	return []string{
 		// Apple macOS + iOS
@@ -0,0 +1,48 @@
+package codegen
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jmpeax.com/guayavita/gvc/internal/loader"
+)
+
+func repoPath(rel string) string {
+	return filepath.Join("..", "..", rel)
+}
+
+func TestBuild_RejectsInvalidPrograms(t *testing.T) {
+	tests := []struct {
+		file    string
+		message string
+	}{
+		{"polymorphic-recursion.gvt", "instantiation of rec exceeds depth"},
+		{"narrow-reassigned.gvt", "a of type i32? may be none"},
+		{"main-params.gvt", "fun main must have no parameters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			program, diags, err := loader.Load(repoPath(filepath.Join("test-data", "invalid", tt.file)), "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(diags) != 0 {
+				t.Fatalf("expected no parse diagnostics, got %d: %#v", len(diags), diags)
+			}
+
+			builder := NewCodeBuilder()
+			builder.SetMode(ModeEmitLLVM).SetOutputDir(t.TempDir())
+			builder.SetDefaultTarget()
+			if err := builder.Build(program.Packages); err == nil {
+				t.Fatalf("expected code generation to fail")
+			}
+			for _, d := range builder.Diagnostics() {
+				if strings.Contains(d.Message, tt.message) {
+					return
+				}
+			}
+			t.Fatalf("expected a diagnostic containing %q, got: %#v", tt.message, builder.Diagnostics())
+		})
+	}
+}
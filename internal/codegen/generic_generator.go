@@ -0,0 +1,437 @@
+package codegen
+
+import (
+	"slices"
+	"strings"
+
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Generic functions are monomorphized: every distinct list of type arguments
+// yields its own LLVM function named pkg.name[T1,T2]. Instantiations are
+// declared at the call site and their bodies are generated once all packages
+// have been generated. Methods of generic types are instantiated the same
+// way, together with their receiver type.
+
+// generateGenericCall infers the type arguments of a call to a generic
+// function from its arguments and the expected result type want, which may
+// be nil, and calls the matching instantiation
+func (b *LLVMCodeBuilder) generateGenericCall(expr *syntax.CallExpr, fn *function, want *Type) (value, error) {
+	decl := fn.decl
+	if len(expr.Args) != len(decl.Params) {
+		return value{}, b.errorAt(expr, "function %s expects %d arguments, got %d", fn.name, len(decl.Params), len(expr.Args))
+	}
+
+	paramTypes := make([]syntax.TypeExpr, 0, len(decl.Params))
+	for _, param := range decl.Params {
+		paramTypes = append(paramTypes, param.Type)
+	}
+	args, typeArgs, err := b.inferTypeArgs(fn.name, decl.TypeParams, fn.scope, paramTypes, expr.Args, decl.Type, want, expr)
+	if err != nil {
+		return value{}, err
+	}
+	if err := b.checkBounds(fn.name, decl.TypeParams, decl.Bounds, fn.scope, typeArgs, expr); err != nil {
+		return value{}, err
+	}
+
+	inst, err := b.instantiate(fn, typeArgs, expr)
+	if err != nil {
+		return value{}, err
+	}
+	return b.emitCall(expr, inst, llvm.Value{}, args)
+}
+
+// emitCall checks pre-generated arguments against the parameters of fn and
+// emits the call
+func (b *LLVMCodeBuilder) emitCall(expr *syntax.CallExpr, fn *function, recv llvm.Value, args []value) (value, error) {
+	llvmArgs := make([]llvm.Value, 0, len(args)+1)
+	if fn.recv != nil {
+		llvmArgs = append(llvmArgs, recv)
+	}
+	for i, arg := range args {
+		arg, err := b.assignable(arg, fn.params[i], expr.Args[i])
+		if err != nil {
+			return value{}, err
+		}
+		llvmArgs = append(llvmArgs, arg.val)
+	}
+
+	// Void calls must not be named
+	name := "call"
+	if fn.result.Kind == KindVoid {
+		name = ""
+	}
+	return value{b.builder.CreateCall(fn.fnType, fn.value, llvmArgs, name), fn.result}, nil
+}
+
+// inferTypeArgs generates args, which are matched against the declared types
+// decls of a generic entity, and infers the type parameters from them. Typed
+// arguments are generated first so that untyped literals can take the type
+// inferred from them. Parameters they leave open are then inferred from the
+// expected type want of the declared result, when both are non-nil.
+func (b *LLVMCodeBuilder) inferTypeArgs(name string, typeParams []string, declScope *scope, decls []syntax.TypeExpr, args []syntax.Expr, result syntax.TypeExpr, want *Type, node syntax.Node) ([]value, []*Type, error) {
+	inferred := make(map[string]*Type, len(typeParams))
+	values := make([]value, len(args))
+	for _, literals := range []bool{false, true} {
+		if literals && result != nil && want != nil {
+			expected := make(map[string]*Type, len(typeParams))
+			if err := b.unify(result, want, typeParams, expected, name, node); err != nil {
+				return nil, nil, err
+			}
+			for param, typ := range expected {
+				if inferred[param] == nil {
+					inferred[param] = typ
+				}
+			}
+		}
+		for i, arg := range args {
+			if isUntypedLiteral(arg) != literals {
+				continue
+			}
+			want, err := b.partialType(decls[i], typeParams, inferred, declScope)
+			if err != nil {
+				return nil, nil, err
+			}
+			v, err := b.generateExprAs(arg, want)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := b.unify(decls[i], v.typ, typeParams, inferred, name, arg); err != nil {
+				return nil, nil, err
+			}
+			values[i] = v
+		}
+	}
+
+	typeArgs := make([]*Type, 0, len(typeParams))
+	for _, param := range typeParams {
+		typ := inferred[param]
+		if typ == nil {
+			return nil, nil, b.errorAt(node, "cannot infer type parameter %s of %s", param, name)
+		}
+		if typ.Kind == KindVoid {
+			return nil, nil, b.errorAt(node, "cannot instantiate %s with %s for %s", name, typ, param)
+		}
+		typeArgs = append(typeArgs, typ)
+	}
+	return values, typeArgs, nil
+}
+
+// partialType resolves a declared type given the type parameters inferred so
+// far; it returns nil while the type mentions a parameter not yet inferred
+func (b *LLVMCodeBuilder) partialType(t syntax.TypeExpr, typeParams []string, inferred map[string]*Type, declScope *scope) (*Type, error) {
+	sc := newScope(declScope)
+	for _, param := range typeParams {
+		if !mentionsTypeParam(t, param) {
+			continue
+		}
+		if inferred[param] == nil {
+			return nil, nil
+		}
+		sc.define(&symbol{kind: symbolType, name: param, typ: inferred[param]})
+	}
+	return b.resolveType(t, sc)
+}
+
+// unify matches a declared type against the type of an argument, binding the
+// type parameters it mentions
+func (b *LLVMCodeBuilder) unify(t syntax.TypeExpr, actual *Type, typeParams []string, inferred map[string]*Type, name string, node syntax.Node) error {
+	if opt, ok := t.(*syntax.OptionalType); ok {
+		// A T argument passed for a T? parameter binds T as well
+		if actual.Kind == KindOptional {
+			actual = actual.Elem
+		}
+		return b.unify(opt.Elem, actual, typeParams, inferred, name, node)
+	}
+	if array, ok := t.(*syntax.ArrayType); ok {
+		if actual.Kind != KindArray || actual.Len != array.Len {
+			return nil
+		}
+		return b.unify(array.Elem, actual.Elem, typeParams, inferred, name, node)
+	}
+	if slice, ok := t.(*syntax.SliceType); ok {
+		if actual.Kind != KindSlice {
+			return nil
+		}
+		return b.unify(slice.Elem, actual.Elem, typeParams, inferred, name, node)
+	}
+	if fn, ok := t.(*syntax.FuncType); ok {
+		if actual.Kind != KindFunc || len(actual.Params) != len(fn.Params) {
+			return nil
+		}
+		for i, param := range fn.Params {
+			if err := b.unify(param, actual.Params[i], typeParams, inferred, name, node); err != nil {
+				return err
+			}
+		}
+		return b.unify(fn.Result, actual.Result, typeParams, inferred, name, node)
+	}
+	if tuple, ok := t.(*syntax.TupleType); ok {
+		if actual.Kind != KindTuple || len(actual.Elems) != len(tuple.Elems) {
+			return nil
+		}
+		for i, elem := range tuple.Elems {
+			if err := b.unify(elem, actual.Elems[i], typeParams, inferred, name, node); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	named, ok := t.(*syntax.NamedType)
+	if !ok {
+		return nil
+	}
+	if named.Pkg == "" && len(named.Args) == 0 && slices.Contains(typeParams, named.Name) {
+		if bound := inferred[named.Name]; bound != nil && bound != actual {
+			return b.errorAt(node, "type parameter %s of %s inferred as both %s and %s", named.Name, name, bound, actual)
+		}
+		inferred[named.Name] = actual
+		return nil
+	}
+	if origin := actual.Origin; origin != nil && origin.Generic.decl.Name == named.Name && len(named.Args) == len(actual.TypeArgs) {
+		for i, arg := range named.Args {
+			if err := b.unify(arg, actual.TypeArgs[i], typeParams, inferred, name, node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mentionsTypeParam reports whether the type parameter param occurs in t
+func mentionsTypeParam(t syntax.TypeExpr, param string) bool {
+	if opt, ok := t.(*syntax.OptionalType); ok {
+		return mentionsTypeParam(opt.Elem, param)
+	}
+	if array, ok := t.(*syntax.ArrayType); ok {
+		return mentionsTypeParam(array.Elem, param)
+	}
+	if slice, ok := t.(*syntax.SliceType); ok {
+		return mentionsTypeParam(slice.Elem, param)
+	}
+	if fn, ok := t.(*syntax.FuncType); ok {
+		for _, p := range fn.Params {
+			if mentionsTypeParam(p, param) {
+				return true
+			}
+		}
+		return mentionsTypeParam(fn.Result, param)
+	}
+	if tuple, ok := t.(*syntax.TupleType); ok {
+		for _, elem := range tuple.Elems {
+			if mentionsTypeParam(elem, param) {
+				return true
+			}
+		}
+		return false
+	}
+	named, ok := t.(*syntax.NamedType)
+	if !ok {
+		return false
+	}
+	if named.Pkg == "" && named.Name == param {
+		return true
+	}
+	for _, arg := range named.Args {
+		if mentionsTypeParam(arg, param) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxInstantiationDepth limits the chain of instances of one generic function
+// that each grow a type argument of the previous one
+const maxInstantiationDepth = 8
+
+// instantiate returns the instantiation of a generic function for the given
+// type arguments, declaring it on first use
+func (b *LLVMCodeBuilder) instantiate(fn *function, typeArgs []*Type, node syntax.Node) (*function, error) {
+	names := make([]string, 0, len(typeArgs))
+	for _, typ := range typeArgs {
+		names = append(names, mangledTypeName(typ))
+	}
+	key := strings.Join(names, ",")
+
+	if inst, ok := fn.instances[key]; ok {
+		return inst, nil
+	}
+	if fn.instances == nil {
+		fn.instances = make(map[string]*function)
+	}
+
+	// Polymorphic recursion, where an instance instantiates its own generic
+	// function with a type argument grown from its own, never runs out of
+	// new instantiations. A body may grow a type argument on purpose, so
+	// only a chain of growing instances deeper than the limit is rejected.
+	var caller *function
+	if b.fn != nil {
+		caller = b.fn.fn
+	}
+	depth := 0
+	for outer := caller; outer != nil; outer = outer.parent {
+		if outer.generic == fn && growsTypeArgs(outer.typeArgs, typeArgs) {
+			depth++
+		}
+	}
+	if depth > maxInstantiationDepth {
+		return nil, b.errorAt(node, "instantiation of %s exceeds depth %d", fn.name, maxInstantiationDepth)
+	}
+
+	// Type parameters are bound as types in a scope nested in the file scope
+	// of the declaration
+	instScope := bindTypeParams(fn.scope, fn.decl.TypeParams, typeArgs, fn.pkg, fn.decl)
+
+	inst, err := b.newFunction(fn.pkg, instScope, fn.decl, nil, fn.pkg.mangle(fn.decl.Name+"["+key+"]"))
+	if err != nil {
+		return nil, err
+	}
+	inst.name = fn.name + "[" + key + "]"
+	inst.generic = fn
+	inst.typeArgs = typeArgs
+	inst.parent = caller
+	inst.value.SetLinkage(linkage(fn.decl.Exported))
+
+	fn.instances[key] = inst
+	b.instances = append(b.instances, inst)
+	return inst, nil
+}
+
+// generateInstances generates the bodies of all instantiated generic
+// functions. Bodies may instantiate further functions, which are appended to
+// the work list.
+func (b *LLVMCodeBuilder) generateInstances() error {
+	saved := b.pkg
+	defer func() { b.pkg = saved }()
+
+	for len(b.instances) > 0 {
+		inst := b.instances[0]
+		b.instances = b.instances[1:]
+
+		b.pkg = inst.pkg
+		if err := b.generateFunctionBody(inst, inst.scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// growsTypeArgs reports whether a type argument of to holds the type argument
+// of from at the same position
+func growsTypeArgs(from, to []*Type) bool {
+	for i, typ := range to {
+		if typ != from[i] && mentionsType(typ, from[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// mentionsType reports whether sub occurs in the structure of t
+func mentionsType(t, sub *Type) bool {
+	if t == sub {
+		return true
+	}
+	if t.Elem != nil && mentionsType(t.Elem, sub) {
+		return true
+	}
+	if t.Result != nil && mentionsType(t.Result, sub) {
+		return true
+	}
+	for _, list := range [][]*Type{t.Elems, t.Params, t.TypeArgs} {
+		for _, elem := range list {
+			if mentionsType(elem, sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mangledTypeName returns the name of a type as used in instantiation
+// symbols; named types are qualified by their package
+func mangledTypeName(t *Type) string {
+	if t.Symbol != "" {
+		return t.Symbol
+	}
+	return t.Name
+}
+
+// genericType holds the declaration of a generic struct or enum. Each
+// instantiation is a separate Type with its own LLVM layout, named
+// pkg.Name<T1,T2>.
+type genericType struct {
+	decl      *syntax.TypeDecl
+	pkg       *packageScope
+	scope     *scope // file scope of the declaration
+	impls     []genericImpl
+	ifaces    []*Type // interfaces every instantiation implements
+	instances map[string]*Type
+}
+
+// genericImpl is an impl block of a generic type; its methods are declared
+// for every instantiation
+type genericImpl struct {
+	decl  *syntax.ImplDecl
+	scope *scope // file scope of the impl block
+}
+
+// instantiateType returns the instantiation of a generic type for the given
+// type arguments, written at node, resolving its members and declaring its
+// methods on first use
+func (b *LLVMCodeBuilder) instantiateType(generic *Type, typeArgs []*Type, node syntax.Node) (*Type, error) {
+	g := generic.Generic
+	names := make([]string, 0, len(typeArgs))
+	keys := make([]string, 0, len(typeArgs))
+	for _, typ := range typeArgs {
+		names = append(names, typ.String())
+		keys = append(keys, mangledTypeName(typ))
+	}
+	key := strings.Join(keys, ",")
+
+	if inst, ok := g.instances[key]; ok {
+		return inst, nil
+	}
+	if g.instances == nil {
+		g.instances = make(map[string]*Type)
+	}
+	if err := b.checkBounds(generic.Name, g.decl.TypeParams, g.decl.Bounds, g.scope, typeArgs, node); err != nil {
+		return nil, err
+	}
+
+	inst := &Type{
+		Kind:     generic.Kind,
+		Name:     generic.Name + "<" + strings.Join(names, ", ") + ">",
+		Symbol:   generic.Symbol + "<" + key + ">",
+		Ifaces:   g.ifaces,
+		Origin:   generic,
+		TypeArgs: typeArgs,
+	}
+	// Registered before the members are resolved so that self references
+	// terminate
+	g.instances[key] = inst
+
+	sc := bindTypeParams(g.scope, g.decl.TypeParams, typeArgs, g.pkg, g.decl)
+	if err := b.resolveTypeBody(inst, g.decl, sc); err != nil {
+		return nil, err
+	}
+
+	for _, impl := range g.impls {
+		if err := b.declareInstanceMethods(inst, impl); err != nil {
+			return nil, err
+		}
+	}
+
+	return inst, nil
+}
+
+// bindTypeParams returns a scope nested in parent binding each type parameter
+// to its type argument
+func bindTypeParams(parent *scope, typeParams []string, typeArgs []*Type, ps *packageScope, decl syntax.Node) *scope {
+	sc := newScope(parent)
+	for i, name := range typeParams {
+		sc.define(&symbol{kind: symbolType, name: name, typ: typeArgs[i], pkg: ps, decl: decl})
+	}
+	return sc
+}
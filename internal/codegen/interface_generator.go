@@ -0,0 +1,259 @@
+package codegen
+
+import (
+	"slices"
+
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Interfaces are implemented explicitly by impl Interface for Type blocks.
+// Bounded type parameters (T: Shape) are still monomorphized, so their
+// methods are called statically. Values of an interface type are lowered to
+// the pair {data, vtable}: data points to a heap copy of the value, which
+// copies of the interface value share, and the vtable holds the methods of
+// its type in the order of the interface. Each slot takes the data pointer
+// as an i8* receiver, like the code of a closure takes its environment.
+
+// resolveInterfaceMethods resolves the method signatures of an interface
+// declaration
+func (b *LLVMCodeBuilder) resolveInterfaceMethods(typ *Type, decl *syntax.InterfaceType, sc *scope) error {
+	for _, method := range decl.Methods {
+		if typ.SigIndex(method.Name) >= 0 {
+			return b.errorAt(method, "duplicate method %s in interface %s", method.Name, typ)
+		}
+		if !method.HasReceiver() {
+			return b.errorAt(method, "interface method %s.%s must take %s", typ, method.Name, syntax.ReceiverName)
+		}
+
+		params := make([]*Type, 0, len(method.Params)-1)
+		for i := range method.Params[1:] {
+			param := &method.Params[i+1]
+			if param.Type == nil {
+				return b.errorAt(param, "%s must be the first parameter of a method", syntax.ReceiverName)
+			}
+			paramType, err := b.resolveType(param.Type, sc)
+			if err != nil {
+				return err
+			}
+			params = append(params, paramType)
+		}
+		result, err := b.resolveType(method.Type, sc)
+		if err != nil {
+			return err
+		}
+		typ.Sigs = append(typ.Sigs, Signature{Name: method.Name, Type: b.funcType(params, result)})
+	}
+	return nil
+}
+
+// declareImplements records the interfaces named by the impl blocks of a
+// package. It runs before any type of the package is instantiated, so that
+// bounds can be checked while members are resolved; the methods themselves
+// are checked by checkImpl once they are declared.
+func (b *LLVMCodeBuilder) declareImplements(ps *packageScope) error {
+	for _, file := range ps.pkg.Files {
+		for _, decl := range file.Decls {
+			d, ok := decl.(*syntax.ImplDecl)
+			if !ok || d.Interface == nil {
+				continue
+			}
+			typ, err := b.implType(ps, d)
+			if err != nil {
+				return err
+			}
+			iface, err := b.resolveInterface(d.Interface, ps.files[file])
+			if err != nil {
+				return err
+			}
+
+			ifaces := &typ.Ifaces
+			if typ.Generic != nil {
+				ifaces = &typ.Generic.ifaces
+			}
+			if slices.Contains(*ifaces, iface) {
+				return b.errorAt(d, "%s already implements %s", typ, iface)
+			}
+			*ifaces = append(*ifaces, iface)
+		}
+	}
+	return nil
+}
+
+// resolveInterface resolves a type that must name an interface
+func (b *LLVMCodeBuilder) resolveInterface(t syntax.TypeExpr, sc *scope) (*Type, error) {
+	iface, err := b.resolveType(t, sc)
+	if err != nil {
+		return nil, err
+	}
+	if iface.Kind != KindInterface {
+		return nil, b.errorAt(t, "%s is not an interface", iface)
+	}
+	return iface, nil
+}
+
+// checkImpl checks that the methods of an impl Interface for Type block,
+// declared on typ, match the methods of the interface
+func (b *LLVMCodeBuilder) checkImpl(typ, iface *Type, decl *syntax.ImplDecl) error {
+	for _, method := range decl.Methods {
+		if iface.SigIndex(method.Name) < 0 {
+			return b.errorAt(method, "method %s is not a member of interface %s", method.Name, iface)
+		}
+	}
+
+	for _, sig := range iface.Sigs {
+		idx := slices.IndexFunc(decl.Methods, func(m *syntax.FunDecl) bool { return m.Name == sig.Name })
+		if idx < 0 {
+			return b.errorAt(decl, "%s does not implement %s (missing method %s)", typ, iface, sig.Name)
+		}
+		fn := typ.Methods[sig.Name]
+		if fn.recv == nil {
+			return b.errorAt(decl.Methods[idx], "method %s.%s must take %s to implement %s", typ, sig.Name, syntax.ReceiverName, iface)
+		}
+		if got := b.funcType(fn.params, fn.result); got != sig.Type {
+			return b.errorAt(decl.Methods[idx], "method %s.%s has type %s, but %s requires %s", typ, sig.Name, got, iface, sig.Type)
+		}
+	}
+	return nil
+}
+
+// implements reports whether values of type t can be used where iface is
+// expected; an interface satisfies itself
+func implements(t, iface *Type) bool {
+	return t == iface || slices.Contains(t.Ifaces, iface)
+}
+
+// checkBoundTypes checks that the bounds of a generic declaration name
+// interfaces
+func (b *LLVMCodeBuilder) checkBoundTypes(bounds []syntax.TypeExpr, sc *scope) error {
+	for _, bound := range bounds {
+		if bound == nil {
+			continue
+		}
+		if _, err := b.resolveInterface(bound, sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBounds checks that the type arguments of an instantiation of the
+// generic entity name implement the bounds of its type parameters
+func (b *LLVMCodeBuilder) checkBounds(name string, typeParams []string, bounds []syntax.TypeExpr, sc *scope, typeArgs []*Type, node syntax.Node) error {
+	for i, bound := range bounds {
+		if bound == nil {
+			continue
+		}
+		iface, err := b.resolveInterface(bound, sc)
+		if err != nil {
+			return err
+		}
+		if !implements(typeArgs[i], iface) {
+			return b.errorAt(node, "%s does not implement %s, required by type parameter %s of %s", typeArgs[i], iface, typeParams[i], name)
+		}
+	}
+	return nil
+}
+
+// vtableType returns the layout of the vtables of an interface: a struct of
+// one function pointer per method
+func (b *LLVMCodeBuilder) vtableType(iface *Type) llvm.Type {
+	if vt, ok := b.vtableTypes[iface]; ok {
+		return vt
+	}
+	if b.vtableTypes == nil {
+		b.vtableTypes = make(map[*Type]llvm.Type)
+	}
+
+	// Registered before the slots are resolved, since methods may take or
+	// return the interface itself
+	vt := b.context.StructCreateNamed(iface.Symbol + ".vtable")
+	b.vtableTypes[iface] = vt
+
+	slots := make([]llvm.Type, 0, len(iface.Sigs))
+	for _, sig := range iface.Sigs {
+		slots = append(slots, llvm.PointerType(b.closureFnType(sig.Type), 0))
+	}
+	vt.StructSetBody(slots, false)
+	return vt
+}
+
+// vtable returns the vtable of typ as an implementation of iface, defining
+// it on first use. The methods take a typed receiver and are cast to the
+// slot type.
+func (b *LLVMCodeBuilder) vtable(typ, iface *Type) llvm.Value {
+	key := [2]*Type{typ, iface}
+	if vt, ok := b.vtables[key]; ok {
+		return vt
+	}
+	if b.vtables == nil {
+		b.vtables = make(map[[2]*Type]llvm.Value)
+	}
+
+	vtType := b.vtableType(iface)
+	slots := make([]llvm.Value, 0, len(iface.Sigs))
+	for _, sig := range iface.Sigs {
+		fn := typ.Methods[sig.Name]
+		slots = append(slots, llvm.ConstBitCast(fn.value, llvm.PointerType(b.closureFnType(sig.Type), 0)))
+	}
+	vt := llvm.AddGlobal(b.module, vtType, iface.Symbol+".vtable["+typ.Symbol+"]")
+	vt.SetInitializer(llvm.ConstNamedStruct(vtType, slots))
+	vt.SetGlobalConstant(true)
+	vt.SetLinkage(llvm.InternalLinkage)
+
+	b.vtables[key] = vt
+	return vt
+}
+
+// toInterface converts a value of a type implementing iface to an interface
+// value, copying it to the heap
+func (b *LLVMCodeBuilder) toInterface(v value, iface *Type) value {
+	raw := b.callExternal("malloc", b.elemSize(v.typ))
+	data := b.builder.CreateBitCast(raw, llvm.PointerType(b.llvmType(v.typ), 0), "data")
+	b.builder.CreateStore(v.val, data)
+
+	agg := llvm.ConstNull(b.llvmType(iface))
+	agg = b.builder.CreateInsertValue(agg, raw, 0, "")
+	agg = b.builder.CreateInsertValue(agg, b.vtable(v.typ, iface), 1, iface.Name)
+	return value{agg, iface}
+}
+
+// generateInterfaceCall calls a method on an interface value stored at recv
+// through its vtable, passing the data pointer as receiver
+func (b *LLVMCodeBuilder) generateInterfaceCall(expr *syntax.CallExpr, callee *syntax.SelectorExpr, iface *Type, recv llvm.Value) (value, error) {
+	idx := iface.SigIndex(callee.Sel)
+	if idx < 0 {
+		return value{}, b.errorAt(callee, "%s.%s undefined (type %s has no method %s)", exprName(callee.X), callee.Sel, iface, callee.Sel)
+	}
+	sig := iface.Sigs[idx].Type
+	if len(expr.Args) != len(sig.Params) {
+		return value{}, b.errorAt(expr, "method %s.%s expects %d arguments, got %d", iface, callee.Sel, len(sig.Params), len(expr.Args))
+	}
+
+	ifaceValue := b.builder.CreateLoad(b.llvmType(iface), recv, "iface")
+	data := b.builder.CreateExtractValue(ifaceValue, 0, "data")
+	vtType := b.vtableType(iface)
+	vt := b.builder.CreateExtractValue(ifaceValue, 1, "vtable")
+	slot := b.builder.CreateStructGEP(vtType, vt, idx, callee.Sel)
+	fnType := b.closureFnType(sig)
+	code := b.builder.CreateLoad(llvm.PointerType(fnType, 0), slot, callee.Sel)
+
+	args := []llvm.Value{data}
+	for i, arg := range expr.Args {
+		v, err := b.generateExprAs(arg, sig.Params[i])
+		if err != nil {
+			return value{}, err
+		}
+		if v, err = b.assignable(v, sig.Params[i], arg); err != nil {
+			return value{}, err
+		}
+		args = append(args, v.val)
+	}
+
+	// Void calls must not be named
+	name := "call"
+	if sig.Result.Kind == KindVoid {
+		name = ""
+	}
+	return value{b.builder.CreateCall(fnType, code, args, name), sig.Result}, nil
+}
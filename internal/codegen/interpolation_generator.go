@@ -0,0 +1,117 @@
+package codegen
+
+import (
+	"strings"
+
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Interpolated strings "a${x}b" are lowered to snprintf from the C library:
+// a first call measures the result and a second writes it to memory from
+// malloc. Integers are formatted in decimal, floats with %g, bools as true or
+// false, runes as the character they encode and strings as they are.
+
+// generateInterpolatedString formats the parts of an interpolated string
+// into a new string
+func (b *LLVMCodeBuilder) generateInterpolatedString(str *syntax.InterpolatedString) (value, error) {
+	var format strings.Builder
+	var args []llvm.Value
+	for _, part := range str.Parts {
+		if lit, ok := part.(*syntax.BasicLit); ok && lit.Kind == "STRING" {
+			format.WriteString(strings.ReplaceAll(lit.Value, "%", "%%"))
+			continue
+		}
+		v, err := b.generateExpr(part)
+		if err != nil {
+			return value{}, err
+		}
+		verb, arg, err := b.formatArg(v, part)
+		if err != nil {
+			return value{}, err
+		}
+		format.WriteString(verb)
+		args = append(args, arg)
+	}
+
+	i8Ptr := llvm.PointerType(b.context.Int8Type(), 0)
+	i64 := b.context.Int64Type()
+	fmtPtr := b.builder.CreateGlobalStringPtr(format.String(), "fmt")
+	measure := append([]llvm.Value{llvm.ConstNull(i8Ptr), llvm.ConstInt(i64, 0, false), fmtPtr}, args...)
+	length := b.builder.CreateSExt(b.callExternal("snprintf", measure...), i64, "")
+	size := b.builder.CreateAdd(length, llvm.ConstInt(i64, 1, false), "size")
+	buf := b.callExternal("malloc", size)
+	b.callExternal("snprintf", append([]llvm.Value{buf, size, fmtPtr}, args...)...)
+	return value{buf, typeString}, nil
+}
+
+// formatArg returns the printf conversion of an interpolated value and the
+// value as passed to printf, promoted as variadic arguments are in C
+func (b *LLVMCodeBuilder) formatArg(v value, node syntax.Node) (string, llvm.Value, error) {
+	i32 := b.context.Int32Type()
+	switch v.typ.Kind {
+	case KindString:
+		return "%s", v.val, nil
+	case KindBool:
+		t := b.builder.CreateGlobalStringPtr("true", "true")
+		f := b.builder.CreateGlobalStringPtr("false", "false")
+		return "%s", b.builder.CreateSelect(v.val, t, f, ""), nil
+	case KindInt:
+		switch {
+		case v.typ == typeRune:
+			return "%s", b.encodeRune(v.val), nil
+		case v.typ.Bits == 64 && v.typ.Signed:
+			return "%lld", v.val, nil
+		case v.typ.Bits == 64:
+			return "%llu", v.val, nil
+		case v.typ.Signed:
+			return "%d", b.builder.CreateSExt(v.val, i32, ""), nil
+		default:
+			return "%u", b.builder.CreateZExt(v.val, i32, ""), nil
+		}
+	case KindFloat:
+		return "%g", b.builder.CreateFPExt(v.val, b.context.DoubleType(), ""), nil
+	}
+	return "", llvm.Value{}, b.errorAt(node, "cannot interpolate value of type %s", v.typ)
+}
+
+// encodeRune writes the UTF-8 encoding of a rune to a stack buffer and
+// returns it as a string. Code points past the last are encoded like the
+// last, in four bytes.
+func (b *LLVMCodeBuilder) encodeRune(r llvm.Value) llvm.Value {
+	i8 := b.context.Int8Type()
+	i32 := b.context.Int32Type()
+	c := func(n uint64) llvm.Value { return llvm.ConstInt(i32, n, false) }
+	below := func(n uint64) llvm.Value { return b.builder.CreateICmp(llvm.IntULT, r, c(n), "") }
+	// cont returns the continuation byte holding the 6 bits of r above shift
+	cont := func(shift uint64) llvm.Value {
+		bits := b.builder.CreateAnd(b.builder.CreateLShr(r, c(shift), ""), c(0x3F), "")
+		return b.builder.CreateOr(bits, c(0x80), "")
+	}
+	lead := func(shift, mark uint64) llvm.Value {
+		return b.builder.CreateOr(b.builder.CreateLShr(r, c(shift), ""), c(mark), "")
+	}
+
+	// Bytes of the encodings in 1, 2, 3 and 4 bytes, chosen by the range of r
+	one, two, three := below(0x80), below(0x800), below(0x10000)
+	pick := func(v1, v2, v3, v4 llvm.Value) llvm.Value {
+		v := b.builder.CreateSelect(three, v3, v4, "")
+		v = b.builder.CreateSelect(two, v2, v, "")
+		return b.builder.CreateSelect(one, v1, v, "")
+	}
+	bytes := []llvm.Value{
+		pick(r, lead(6, 0xC0), lead(12, 0xE0), lead(18, 0xF0)),
+		pick(c(0), cont(0), cont(6), cont(12)),
+		pick(c(0), c(0), cont(0), cont(6)),
+		pick(c(0), c(0), c(0), cont(0)),
+	}
+
+	bufType := llvm.ArrayType(i8, 5)
+	buf := b.createEntryAlloca(bufType, "rune")
+	zero := c(0)
+	for i, v := range append(bytes, c(0)) {
+		ptr := b.builder.CreateGEP(bufType, buf, []llvm.Value{zero, c(uint64(i))}, "")
+		b.builder.CreateStore(b.builder.CreateTrunc(v, i8, ""), ptr)
+	}
+	return b.builder.CreateGEP(bufType, buf, []llvm.Value{zero, zero}, "runestr")
+}
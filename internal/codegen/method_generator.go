@@ -0,0 +1,238 @@
+package codegen
+
+import (
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// declareImpl declares the methods of an impl block on their receiver type.
+// Method symbols are mangled as pkg.Type.method so that methods of different
+// types never collide. Impl blocks of generic types are recorded and declared
+// for every instantiation of the type.
+func (b *LLVMCodeBuilder) declareImpl(ps *packageScope, fileScope *scope, decl *syntax.ImplDecl) error {
+	typ, err := b.implType(ps, decl)
+	if err != nil {
+		return err
+	}
+
+	if typ.Generic == nil {
+		if len(decl.TypeParams) > 0 {
+			return b.errorAt(decl, "type %s is not generic", typ)
+		}
+		if err := b.declareMethods(ps, typ, decl, fileScope, false); err != nil {
+			return err
+		}
+		return b.checkInterfaceImpl(typ, decl, fileScope)
+	}
+
+	if want := len(typ.Generic.decl.TypeParams); len(decl.TypeParams) != want {
+		return b.errorAt(decl, "impl of generic type %s must declare %d type parameters", typ, want)
+	}
+	impl := genericImpl{decl: decl, scope: fileScope}
+	typ.Generic.impls = append(typ.Generic.impls, impl)
+
+	// Instantiations created before the impl block was seen
+	for _, inst := range typ.Generic.instances {
+		if err := b.declareInstanceMethods(inst, impl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// declareInstanceMethods declares the methods of a generic impl block for one
+// instantiation of its type, binding the impl type parameters to the type
+// arguments
+func (b *LLVMCodeBuilder) declareInstanceMethods(inst *Type, impl genericImpl) error {
+	g := inst.Origin.Generic
+	sc := bindTypeParams(impl.scope, impl.decl.TypeParams, inst.TypeArgs, g.pkg, impl.decl)
+	if err := b.declareMethods(g.pkg, inst, impl.decl, sc, true); err != nil {
+		return err
+	}
+	return b.checkInterfaceImpl(inst, impl.decl, sc)
+}
+
+// checkInterfaceImpl checks the methods of an impl Interface for Type block
+// against the interface; inherent impl blocks need no check
+func (b *LLVMCodeBuilder) checkInterfaceImpl(typ *Type, decl *syntax.ImplDecl, sc *scope) error {
+	if decl.Interface == nil {
+		return nil
+	}
+	iface, err := b.resolveInterface(decl.Interface, sc)
+	if err != nil {
+		return err
+	}
+	return b.checkImpl(typ, iface, decl)
+}
+
+// declareMethods declares the methods of decl on typ, resolving signatures in
+// sc. Bodies of instantiated methods are generated with the other instances.
+func (b *LLVMCodeBuilder) declareMethods(ps *packageScope, typ *Type, decl *syntax.ImplDecl, sc *scope, instance bool) error {
+	if typ.Methods == nil {
+		typ.Methods = make(map[string]*function)
+	}
+	// Methods are visible wherever their type is
+	exported := ps.scope.lookupLocal(decl.Type).isExported()
+
+	for _, method := range decl.Methods {
+		if len(method.TypeParams) > 0 {
+			return b.errorAt(method, "method %s.%s cannot have type parameters", typ, method.Name)
+		}
+		if _, exists := typ.Methods[method.Name]; exists {
+			return b.errorAt(method, "method %s.%s redeclared", typ, method.Name)
+		}
+		fn, err := b.newFunction(ps, sc, method, typ, typ.Symbol+"."+method.Name)
+		if err != nil {
+			return err
+		}
+		fn.name = typ.Name + "." + method.Name
+		fn.value.SetLinkage(linkage(exported))
+		typ.Methods[method.Name] = fn
+		if instance {
+			b.instances = append(b.instances, fn)
+		}
+	}
+
+	return nil
+}
+
+// implType resolves the receiver type of an impl block, which must be declared
+// in the same package
+func (b *LLVMCodeBuilder) implType(ps *packageScope, decl *syntax.ImplDecl) (*Type, error) {
+	sym := ps.scope.lookupLocal(decl.Type)
+	if sym == nil || sym.kind != symbolType {
+		if _, primitive := primitiveTypes[decl.Type]; primitive {
+			return nil, b.errorAt(decl, "cannot define methods on non-local type %s", decl.Type)
+		}
+		return nil, b.errorAt(decl, "unknown type: %s", decl.Type)
+	}
+	if sym.typ.Kind == KindInterface {
+		return nil, b.errorAt(decl, "cannot define methods on interface %s", sym.typ)
+	}
+	return sym.typ, nil
+}
+
+// generateImplDecl generates the bodies of the methods of an impl block
+func (b *LLVMCodeBuilder) generateImplDecl(decl *syntax.ImplDecl) error {
+	typ, err := b.implType(b.pkg, decl)
+	if err != nil {
+		return err
+	}
+	if typ.Generic != nil {
+		// Methods of generic types are generated per instantiation
+		return nil
+	}
+	for _, method := range decl.Methods {
+		if err := b.generateFunctionBody(typ.Methods[method.Name], b.fn.scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateMethodCall calls a method on a value: value.method(args). The
+// receiver is passed by address; temporaries and top-level constants are
+// copied to a stack slot so that methods cannot modify them.
+func (b *LLVMCodeBuilder) generateMethodCall(expr *syntax.CallExpr, callee *syntax.SelectorExpr) (value, error) {
+	var recv llvm.Value
+	var typ *Type
+	ok := false
+	if root := b.rootSymbol(callee.X); root == nil || !root.constant {
+		var err error
+		if recv, typ, ok, err = b.generateAddr(callee.X); err != nil {
+			return value{}, err
+		}
+	}
+	if !ok {
+		v, err := b.generateExpr(callee.X)
+		if err != nil {
+			return value{}, err
+		}
+		typ = v.typ
+		if typ.Kind != KindVoid {
+			recv = b.createEntryAlloca(b.llvmType(typ), "recv")
+			b.builder.CreateStore(v.val, recv)
+		}
+	}
+
+	if typ.Kind == KindInterface {
+		return b.generateInterfaceCall(expr, callee, typ, recv)
+	}
+	fn := typ.Methods[callee.Sel]
+	if fn == nil {
+		// A field holding a function value is called like a method
+		if idx := typ.FieldIndex(callee.Sel); typ.Kind == KindStruct && idx >= 0 && typ.Fields[idx].Type.Kind == KindFunc {
+			field := typ.Fields[idx].Type
+			ptr := b.builder.CreateStructGEP(b.llvmType(typ), recv, idx, callee.Sel)
+			return b.generateClosureCall(expr, value{b.builder.CreateLoad(b.llvmType(field), ptr, callee.Sel), field})
+		}
+		return value{}, b.errorAt(callee, "%s.%s undefined (type %s has no method %s)", exprName(callee.X), callee.Sel, typ, callee.Sel)
+	}
+	if fn.recv == nil {
+		return value{}, b.errorAt(callee, "method %s has no %s receiver; call it as %s(...)", fn.name, syntax.ReceiverName, fn.name)
+	}
+	return b.generateDirectCall(expr, fn, recv)
+}
+
+// generateTypeCall handles calls qualified by a type name: enum variant
+// constructors and methods without receiver, as in Shape.Circle(r) or
+// Point.origin()
+func (b *LLVMCodeBuilder) generateTypeCall(expr *syntax.CallExpr, callee *syntax.SelectorExpr, typ *Type, want *Type) (value, error) {
+	if isVariant(typ, callee.Sel) {
+		return b.generateVariant(callee, typ, expr.Args, want)
+	}
+	if typ.Generic != nil {
+		return b.generateGenericTypeCall(expr, callee, typ, want)
+	}
+
+	fn := typ.Methods[callee.Sel]
+	if fn == nil {
+		return value{}, b.errorAt(callee, "%s.%s undefined (type %s has no method %s)", exprName(callee.X), callee.Sel, typ, callee.Sel)
+	}
+	if fn.recv != nil {
+		return value{}, b.errorAt(callee, "method %s must be called on a value of type %s", fn.name, typ)
+	}
+	return b.generateDirectCall(expr, fn, llvm.Value{})
+}
+
+// generateGenericTypeCall calls a method without receiver of a generic type,
+// as in Box.new(v). The instantiation is the expected type or is inferred from
+// the arguments.
+func (b *LLVMCodeBuilder) generateGenericTypeCall(expr *syntax.CallExpr, callee *syntax.SelectorExpr, generic *Type, want *Type) (value, error) {
+	var impl *genericImpl
+	var method *syntax.FunDecl
+	for i := range generic.Generic.impls {
+		for _, m := range generic.Generic.impls[i].decl.Methods {
+			if m.Name == callee.Sel {
+				impl, method = &generic.Generic.impls[i], m
+			}
+		}
+	}
+	if method == nil {
+		return value{}, b.errorAt(callee, "%s.%s undefined (type %s has no method %s)", exprName(callee.X), callee.Sel, generic, callee.Sel)
+	}
+	if method.HasReceiver() {
+		return value{}, b.errorAt(callee, "method %s.%s must be called on a value of type %s", generic, method.Name, generic)
+	}
+
+	if want != nil && want.Origin == generic {
+		return b.generateDirectCall(expr, want.Methods[method.Name], llvm.Value{})
+	}
+
+	if len(expr.Args) != len(method.Params) {
+		return value{}, b.errorAt(expr, "function %s.%s expects %d arguments, got %d", generic, method.Name, len(method.Params), len(expr.Args))
+	}
+	paramTypes := make([]syntax.TypeExpr, 0, len(method.Params))
+	for _, param := range method.Params {
+		paramTypes = append(paramTypes, param.Type)
+	}
+	args, typeArgs, err := b.inferTypeArgs(generic.Name+"."+method.Name, impl.decl.TypeParams, impl.scope, paramTypes, expr.Args, nil, nil, expr)
+	if err != nil {
+		return value{}, err
+	}
+	inst, err := b.instantiateType(generic, typeArgs, expr)
+	if err != nil {
+		return value{}, err
+	}
+	return b.emitCall(expr, inst.Methods[method.Name], llvm.Value{}, args)
+}
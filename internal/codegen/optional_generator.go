@@ -0,0 +1,181 @@
+package codegen
+
+import (
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Optional types T? are lowered to the pair {i1 present, T value}; none is the
+// zero pair. A T is implicitly wrapped where a T? is expected, but a T? is
+// only usable as a T once a none check narrows it: inside if x != none, in
+// the else branch of if x == none, in the body of while x != none, and after
+// an if x == none whose body always leaves the block. A narrowed variable
+// can still be assigned a T?, which ends the narrowing.
+
+// optionalType returns the optional type of elem. Optional types are
+// interned so that identity stays pointer equality.
+func (b *LLVMCodeBuilder) optionalType(elem *Type) *Type {
+	if b.optionals == nil {
+		b.optionals = make(map[*Type]*Type)
+	}
+	if opt, ok := b.optionals[elem]; ok {
+		return opt
+	}
+	opt := &Type{
+		Kind:   KindOptional,
+		Name:   elem.Name + "?",
+		Symbol: mangledTypeName(elem) + "?",
+		Elem:   elem,
+	}
+	b.optionals[elem] = opt
+	return opt
+}
+
+// isNone reports whether expr is the none literal
+func isNone(expr syntax.Expr) bool {
+	lit, ok := expr.(*syntax.BasicLit)
+	return ok && lit.Kind == "NONE"
+}
+
+// generateNone generates none as a value of the expected optional type
+func (b *LLVMCodeBuilder) generateNone(lit *syntax.BasicLit, want *Type) (value, error) {
+	if want == nil {
+		return value{}, b.errorAt(lit, "cannot infer the type of none; declare an optional type")
+	}
+	if want.Kind != KindOptional {
+		return value{}, b.errorAt(lit, "cannot use none as non-optional type %s", want)
+	}
+	return value{llvm.ConstNull(b.llvmType(want)), want}, nil
+}
+
+// wrapOptional converts a value of the element type of opt to a present opt
+func (b *LLVMCodeBuilder) wrapOptional(v llvm.Value, opt *Type) llvm.Value {
+	pair := llvm.ConstNull(b.llvmType(opt))
+	pair = b.builder.CreateInsertValue(pair, llvm.ConstInt(b.context.Int1Type(), 1, false), 0, "")
+	return b.builder.CreateInsertValue(pair, v, 1, "opt")
+}
+
+// optionalUseError reports the use of an optional that was not narrowed
+func (b *LLVMCodeBuilder) optionalUseError(expr syntax.Expr, typ *Type) error {
+	name := exprName(expr)
+	return b.errorAt(expr, "%s of type %s may be none; check it with if %s != none before use", name, typ, name)
+}
+
+// generateNoneComparison lowers x == none and x != none to a test of the
+// present flag of x
+func (b *LLVMCodeBuilder) generateNoneComparison(expr *syntax.BinaryExpr) (value, error) {
+	operand := expr.Left
+	if isNone(operand) {
+		operand = expr.Right
+	}
+	if expr.Op != "==" && expr.Op != "!=" {
+		return value{}, b.errorAt(expr, "invalid operation: operator %s not defined on none", expr.Op)
+	}
+	if isNone(operand) {
+		return value{}, b.errorAt(expr, "invalid operation: none %s none", expr.Op)
+	}
+
+	v, err := b.generateExpr(operand)
+	if err != nil {
+		return value{}, err
+	}
+	if v.typ.Kind != KindOptional {
+		return value{}, b.errorAt(expr, "cannot compare non-optional type %s with none", v.typ)
+	}
+
+	present := b.builder.CreateExtractValue(v.val, 0, "present")
+	if expr.Op == "==" {
+		return value{b.builder.CreateNot(present, "absent"), typeBool}, nil
+	}
+	return value{present, typeBool}, nil
+}
+
+// noneChecks returns the variables a condition proves present when it holds
+// and when it does not. Checks combine through &&, || and !.
+func noneChecks(cond syntax.Expr) (then, otherwise []string) {
+	switch e := cond.(type) {
+	case *syntax.UnaryExpr:
+		if e.Op == "!" {
+			then, otherwise = noneChecks(e.X)
+			return otherwise, then
+		}
+	case *syntax.BinaryExpr:
+		switch e.Op {
+		case "&&":
+			left, _ := noneChecks(e.Left)
+			right, _ := noneChecks(e.Right)
+			return append(left, right...), nil
+		case "||":
+			_, left := noneChecks(e.Left)
+			_, right := noneChecks(e.Right)
+			return nil, append(left, right...)
+		case "==", "!=":
+			operand := e.Left
+			if isNone(operand) {
+				operand = e.Right
+			} else if !isNone(e.Right) {
+				return nil, nil
+			}
+			ident, ok := operand.(*syntax.Ident)
+			if !ok {
+				return nil, nil
+			}
+			if e.Op == "!=" {
+				return []string{ident.Name}, nil
+			}
+			return nil, []string{ident.Name}
+		}
+	}
+	return nil, nil
+}
+
+// narrow rebinds the named optional locals in the current scope to their
+// value slot, so that they are read as their element type. Globals and
+// locals assigned by closures are never narrowed since any call may reset
+// them.
+func (b *LLVMCodeBuilder) narrow(names []string) {
+	for _, name := range names {
+		sym := b.fn.scope.lookup(name)
+		if sym == nil || sym.kind != symbolVar || sym.typ.Kind != KindOptional || !sym.ptr.IsAGlobalVariable().IsNil() || b.fn.reassigned[name] {
+			continue
+		}
+		narrowed := *sym
+		narrowed.narrowed = sym
+		narrowed.typ = sym.typ.Elem
+		narrowed.ptr = b.builder.CreateStructGEP(b.llvmType(sym.typ), sym.ptr, 1, name)
+		narrowed.loops = len(b.fn.loops)
+		b.fn.scope.define(&narrowed)
+	}
+}
+
+// assignNarrowed assigns to a narrowed optional. A value of the element type
+// keeps it narrowed; a T?, none included, is stored as is and ends the
+// narrowing for the rest of the scope the none check narrowed. Code of an
+// inner loop may run again after such an assignment, still reading the
+// variable as narrowed, so it is rejected there.
+func (b *LLVMCodeBuilder) assignNarrowed(stmt *syntax.AssignStmt, sym *symbol) error {
+	opt := sym.narrowed
+	right, err := b.generateExprAs(stmt.Right, opt.typ)
+	if err != nil {
+		return err
+	}
+	if right.typ == sym.typ {
+		b.builder.CreateStore(right.val, sym.ptr)
+		return nil
+	}
+	if len(b.fn.loops) > sym.loops {
+		return b.errorAt(stmt.Right, "cannot assign %s to %s in a loop inside the none check that narrows it to %s", right.typ, sym.name, sym.typ)
+	}
+	if right, err = b.assignable(right, opt.typ, stmt.Right); err != nil {
+		return err
+	}
+	b.builder.CreateStore(right.val, opt.ptr)
+
+	for sc := b.fn.scope; sc != nil; sc = sc.parent {
+		if sc.lookupLocal(sym.name) == sym {
+			sc.define(opt)
+			break
+		}
+	}
+	return nil
+}
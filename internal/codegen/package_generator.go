@@ -0,0 +1,185 @@
+package codegen
+
+import (
+	"jmpeax.com/guayavita/gvc/internal/diag"
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// packageScope holds the top-level symbols of a package and the scopes of its
+// files, which additionally see the packages and symbols each file imports
+type packageScope struct {
+	path  string
+	pkg   *syntax.Package
+	scope *scope
+	files map[*syntax.File]*scope
+	init  llvm.Value // initializes the package globals
+}
+
+// mangle returns the LLVM symbol name of a top-level declaration
+func (p *packageScope) mangle(name string) string {
+	return p.path + "." + name
+}
+
+// declarePackage creates the package scope, binds file imports, and declares
+// all types, functions and methods so they can be referenced before their definition
+func (b *LLVMCodeBuilder) declarePackage(pkg *syntax.Package) (*packageScope, error) {
+	ps := &packageScope{
+		path:  pkg.Path,
+		pkg:   pkg,
+		scope: newScope(b.universe),
+		files: make(map[*syntax.File]*scope),
+	}
+	b.packages[pkg.Path] = ps
+
+	for _, file := range pkg.Files {
+		fileScope, err := b.bindImports(ps, file)
+		if err != nil {
+			return nil, err
+		}
+		ps.files[file] = fileScope
+	}
+
+	if err := b.declareTypes(ps); err != nil {
+		return nil, err
+	}
+
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			var err error
+			switch d := decl.(type) {
+			case *syntax.FunDecl:
+				err = b.declareFunction(ps, ps.files[file], d)
+			case *syntax.ImplDecl:
+				err = b.declareImpl(ps, ps.files[file], d)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	initType := llvm.FunctionType(b.context.VoidType(), []llvm.Type{}, false)
+	ps.init = llvm.AddFunction(b.module, ps.mangle("$init"), initType)
+	ps.init.SetLinkage(llvm.InternalLinkage)
+
+	return ps, nil
+}
+
+// bindImports creates the scope of a file, binding imported packages under
+// their name or alias and imported symbols under their own name
+func (b *LLVMCodeBuilder) bindImports(ps *packageScope, file *syntax.File) (*scope, error) {
+	fileScope := newScope(ps.scope)
+
+	for _, imp := range file.Imports {
+		dep, ok := b.packages[imp.Path]
+		if !ok {
+			return nil, b.errorAt(imp, "package %s is not loaded", imp.Path)
+		}
+
+		if len(imp.Symbols) == 0 || imp.Alias != "" {
+			name := imp.Name()
+			if fileScope.lookupLocal(name) != nil {
+				return nil, b.errorAt(imp, "%s redeclared in this file", name)
+			}
+			fileScope.bind(name, &symbol{kind: symbolPackage, name: name, pkg: dep, decl: imp})
+		}
+
+		for _, name := range imp.Symbols {
+			sym := dep.scope.lookupLocal(name)
+			if sym == nil {
+				return nil, b.errorAt(imp, "package %s has no symbol %s", imp.Path, name)
+			}
+			if err := b.checkExported(imp, sym, name); err != nil {
+				return nil, err
+			}
+			if fileScope.lookupLocal(name) != nil {
+				return nil, b.errorAt(imp, "%s redeclared in this file", name)
+			}
+			fileScope.bind(name, sym)
+		}
+	}
+
+	return fileScope, nil
+}
+
+// generatePackage generates the package initializer and every function body
+func (b *LLVMCodeBuilder) generatePackage(ps *packageScope) error {
+	saved := b.pkg
+	b.pkg = ps
+	defer func() { b.pkg = saved }()
+
+	// Globals are initialized in declaration order inside the package initializer
+	entry := b.context.AddBasicBlock(ps.init, "entry")
+	for _, file := range ps.pkg.Files {
+		b.fn = &funcState{value: ps.init, scope: ps.files[file]}
+		b.builder.SetInsertPointAtEnd(entry)
+		for _, decl := range file.Decls {
+			if d, ok := decl.(*syntax.VarDecl); ok {
+				if err := b.generateDecl(d); err != nil {
+					return err
+				}
+				entry = b.builder.GetInsertBlock()
+			}
+		}
+	}
+	b.builder.SetInsertPointAtEnd(entry)
+	b.builder.CreateRetVoid()
+
+	for _, file := range ps.pkg.Files {
+		b.fn = &funcState{value: ps.init, scope: ps.files[file]}
+		for _, decl := range file.Decls {
+			if _, ok := decl.(*syntax.VarDecl); ok {
+				continue
+			}
+			if err := b.generateDecl(decl); err != nil {
+				return err
+			}
+		}
+	}
+	b.fn = nil
+
+	return nil
+}
+
+// lookupQualified resolves a pkg.name reference; ok is false when x does not
+// name an imported package
+func (b *LLVMCodeBuilder) lookupQualified(expr *syntax.SelectorExpr) (*symbol, bool, error) {
+	ident, isIdent := expr.X.(*syntax.Ident)
+	if !isIdent {
+		return nil, false, nil
+	}
+	pkgSym := b.fn.scope.lookup(ident.Name)
+	if pkgSym == nil || pkgSym.kind != symbolPackage {
+		return nil, false, nil
+	}
+
+	sym := pkgSym.pkg.scope.lookupLocal(expr.Sel)
+	if sym == nil {
+		return nil, true, b.errorAt(expr, "undefined: %s.%s", ident.Name, expr.Sel)
+	}
+	if err := b.checkExported(expr, sym, ident.Name+"."+expr.Sel); err != nil {
+		return nil, true, err
+	}
+	return sym, true, nil
+}
+
+// checkExported rejects a reference from another package, at node, to a
+// symbol that is not exported, adding a note at its declaration
+func (b *LLVMCodeBuilder) checkExported(node syntax.Node, sym *symbol, name string) error {
+	if sym.isExported() {
+		return nil
+	}
+	err := b.errorAt(node, "%s is not exported by package %s", name, sym.pkg.path)
+	b.addDiagnostic(diag.Note, sym.decl.Pos(), sym.name+" is declared here; declare it with export to use it from other packages")
+	return err
+}
+
+// linkage returns the LLVM linkage of a top-level symbol: symbols that are
+// not exported are internal to the module, so unused ones can be removed
+func linkage(exported bool) llvm.Linkage {
+	if exported {
+		return llvm.ExternalLinkage
+	}
+	return llvm.InternalLinkage
+}
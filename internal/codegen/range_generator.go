@@ -0,0 +1,275 @@
+package codegen
+
+import (
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Ranges a..b and a..=b are not values: they drive counted for-in loops,
+// which need no allocation, and select slices as index bounds.
+
+// generateForInStmt generates LLVM IR for a for-in loop
+func (b *LLVMCodeBuilder) generateForInStmt(stmt *syntax.ForInStmt) error {
+	if rng, ok := stmt.Iter.(*syntax.RangeExpr); ok {
+		return b.generateRangeLoop(stmt, rng)
+	}
+	iter, err := b.generateExpr(stmt.Iter)
+	if err != nil {
+		return err
+	}
+	switch iter.typ.Kind {
+	case KindArray:
+		return b.generateArrayLoop(stmt, iter)
+	case KindSlice:
+		return b.generateSliceLoop(stmt, iter)
+	}
+	return b.errorAt(stmt.Iter, "cannot range over %s of type %s", exprName(stmt.Iter), iter.typ)
+}
+
+// generateRangeLoop lowers a for-in loop over a range to a counted loop. The
+// counter is separate from the loop variable, so assigning the variable does
+// not change the iteration. Inclusive ranges stop after the upper bound
+// instead of comparing past it, so that ranges ending at the maximum value
+// of their type terminate.
+func (b *LLVMCodeBuilder) generateRangeLoop(stmt *syntax.ForInStmt, rng *syntax.RangeExpr) error {
+	if rng.Low == nil || rng.High == nil {
+		return b.errorAt(rng, "range in for-in loop requires both bounds")
+	}
+	low, high, err := b.generateRangeBounds(rng)
+	if err != nil {
+		return err
+	}
+	typ := low.typ
+
+	llvmType := b.llvmType(typ)
+	counter := b.createEntryAlloca(llvmType, stmt.Var+".counter")
+	b.builder.CreateStore(low.val, counter)
+
+	condBlock := b.context.AddBasicBlock(b.fn.value, "forin.cond")
+	bodyBlock := b.context.AddBasicBlock(b.fn.value, "forin.body")
+	postBlock := b.context.AddBasicBlock(b.fn.value, "forin.post")
+	exitBlock := b.context.AddBasicBlock(b.fn.value, "forin.end")
+
+	preds := unsignedPredicates
+	if typ.Signed {
+		preds = signedPredicates
+	}
+	op := "<"
+	if rng.Inclusive {
+		op = "<="
+	}
+
+	b.builder.CreateBr(condBlock)
+	b.builder.SetInsertPointAtEnd(condBlock)
+	current := b.builder.CreateLoad(llvmType, counter, "i")
+	b.builder.CreateCondBr(b.builder.CreateICmp(preds[op], current, high.val, "cmp"), bodyBlock, exitBlock)
+
+	b.builder.SetInsertPointAtEnd(bodyBlock)
+	b.pushScope()
+	ptr := b.createLocal(typ, stmt.Var)
+	b.builder.CreateStore(current, ptr)
+	b.fn.scope.define(&symbol{kind: symbolVar, name: stmt.Var, typ: typ, ptr: ptr, pkg: b.pkg, decl: stmt})
+	err = b.generateLoopBody(stmt, stmt.Label, stmt.Body, nil, exitBlock, postBlock)
+	b.popScope()
+	if err != nil {
+		return err
+	}
+	if !b.isTerminated() {
+		b.builder.CreateBr(postBlock)
+	}
+
+	b.builder.SetInsertPointAtEnd(postBlock)
+	current = b.builder.CreateLoad(llvmType, counter, "i")
+	if rng.Inclusive {
+		nextBlock := b.context.InsertBasicBlock(exitBlock, "forin.next")
+		b.builder.CreateCondBr(b.builder.CreateICmp(llvm.IntEQ, current, high.val, "last"), exitBlock, nextBlock)
+		b.builder.SetInsertPointAtEnd(nextBlock)
+	}
+	b.builder.CreateStore(b.builder.CreateAdd(current, llvm.ConstInt(llvmType, 1, false), "next"), counter)
+	b.builder.CreateBr(condBlock)
+
+	b.builder.SetInsertPointAtEnd(exitBlock)
+	return nil
+}
+
+// generateRangeBounds generates both bounds of a range, which must be
+// integers of the same type. Like the operands of a binary expression, an
+// untyped literal bound takes the type of the other one.
+func (b *LLVMCodeBuilder) generateRangeBounds(rng *syntax.RangeExpr) (low, high value, err error) {
+	if isUntypedLiteral(rng.Low) && !isUntypedLiteral(rng.High) {
+		if high, err = b.generateExpr(rng.High); err != nil {
+			return value{}, value{}, err
+		}
+		if low, err = b.generateExprAs(rng.Low, high.typ); err != nil {
+			return value{}, value{}, err
+		}
+	} else {
+		if low, err = b.generateExpr(rng.Low); err != nil {
+			return value{}, value{}, err
+		}
+		if high, err = b.generateExprAs(rng.High, low.typ); err != nil {
+			return value{}, value{}, err
+		}
+	}
+
+	if low.typ.Kind != KindInt {
+		return value{}, value{}, b.errorAt(rng.Low, "range bound must be an integer, got %s", low.typ)
+	}
+	if low.typ != high.typ {
+		return value{}, value{}, b.errorAt(rng, "mismatched range bounds %s and %s", low.typ, high.typ)
+	}
+	return low, high, nil
+}
+
+// generateIndexExpr generates LLVM IR for an index expression: an element of
+// an array or a slice, or a string, an array or a slice sliced by a range
+func (b *LLVMCodeBuilder) generateIndexExpr(expr *syntax.IndexExpr) (value, error) {
+	ptr, typ, ok, err := b.generateAddr(expr)
+	if err != nil {
+		return value{}, err
+	}
+	if ok {
+		return value{b.builder.CreateLoad(b.llvmType(typ), ptr, "elem"), typ}, nil
+	}
+
+	x, err := b.generateExpr(expr.X)
+	if err != nil {
+		return value{}, err
+	}
+	rng, isRange := expr.Index.(*syntax.RangeExpr)
+	switch {
+	case x.typ.Kind == KindString && isRange:
+		return b.generateStringSlice(expr, x, rng)
+	case x.typ.Kind == KindString:
+		return b.generateStringIndex(expr, x)
+	case x.typ.Kind == KindArray && isRange:
+		tmp := b.createEntryAlloca(b.llvmType(x.typ), "array")
+		b.builder.CreateStore(x.val, tmp)
+		zero := llvm.ConstInt(b.context.Int64Type(), 0, false)
+		data := b.builder.CreateGEP(b.llvmType(x.typ), tmp, []llvm.Value{zero, zero}, "data")
+		length := llvm.ConstInt(b.context.Int64Type(), uint64(x.typ.Len), false)
+		return b.generateElemSlice(expr, x.typ.Elem, data, length, rng)
+	case x.typ.Kind == KindArray:
+		return b.generateArrayIndex(expr, x)
+	case x.typ.Kind == KindSlice && isRange:
+		data := b.builder.CreateExtractValue(x.val, 0, "data")
+		length := b.builder.CreateExtractValue(x.val, 1, "len")
+		return b.generateElemSlice(expr, x.typ.Elem, data, length, rng)
+	case x.typ.Kind == KindSlice:
+		ptr, err := b.sliceElemAddr(expr, x)
+		if err != nil {
+			return value{}, err
+		}
+		return value{b.builder.CreateLoad(b.llvmType(x.typ.Elem), ptr, "elem"), x.typ.Elem}, nil
+	}
+	return value{}, b.errorAt(expr, "cannot index %s of type %s", exprName(expr.X), x.typ)
+}
+
+// generateStringIndex reads the byte of s at an index, which is checked
+// against the length of s at run time
+func (b *LLVMCodeBuilder) generateStringIndex(expr *syntax.IndexExpr, s value) (value, error) {
+	idx, err := b.generateOffset(expr.Index)
+	if err != nil {
+		return value{}, err
+	}
+	length := b.callExternal("strlen", s.val)
+	b.generateCheck(b.builder.CreateICmp(llvm.IntULT, idx, length, "inbounds"), expr, "index out of range for string")
+	ptr := b.builder.CreateGEP(b.context.Int8Type(), s.val, []llvm.Value{idx}, "")
+	return value{b.builder.CreateLoad(b.context.Int8Type(), ptr, "byte"), typeByte}, nil
+}
+
+// generateStringSlice copies the bytes of s selected by a range into a new
+// string. Omitted bounds default to the start and the end of s; the bounds
+// are checked at run time.
+func (b *LLVMCodeBuilder) generateStringSlice(expr *syntax.IndexExpr, s value, rng *syntax.RangeExpr) (value, error) {
+	i64 := b.context.Int64Type()
+	length := b.callExternal("strlen", s.val)
+	low, high, err := b.sliceBounds(rng, length)
+	if err != nil {
+		return value{}, err
+	}
+
+	inBounds := b.builder.CreateAnd(
+		b.builder.CreateICmp(llvm.IntULE, low, high, ""),
+		b.builder.CreateICmp(llvm.IntULE, high, length, ""), "inbounds")
+	b.generateCheck(inBounds, expr, "slice bounds out of range")
+
+	n := b.builder.CreateSub(high, low, "n")
+	buf := b.callExternal("malloc", b.builder.CreateAdd(n, llvm.ConstInt(i64, 1, false), ""))
+	src := b.builder.CreateGEP(b.context.Int8Type(), s.val, []llvm.Value{low}, "")
+	b.callExternal("memcpy", buf, src, n)
+	end := b.builder.CreateGEP(b.context.Int8Type(), buf, []llvm.Value{n}, "")
+	b.builder.CreateStore(llvm.ConstInt(b.context.Int8Type(), 0, false), end)
+	return value{buf, typeString}, nil
+}
+
+// generateElemSlice copies the elements selected by a range, out of length
+// elements at data, into a new slice. Like string slices, the result does
+// not share storage with its operand, so appending to it cannot clobber the
+// elements that follow. The bounds are checked at run time.
+func (b *LLVMCodeBuilder) generateElemSlice(expr *syntax.IndexExpr, elem *Type, data, length llvm.Value, rng *syntax.RangeExpr) (value, error) {
+	low, high, err := b.sliceBounds(rng, length)
+	if err != nil {
+		return value{}, err
+	}
+
+	inBounds := b.builder.CreateAnd(
+		b.builder.CreateICmp(llvm.IntULE, low, high, ""),
+		b.builder.CreateICmp(llvm.IntULE, high, length, ""), "inbounds")
+	b.generateCheck(inBounds, expr, "slice bounds out of range")
+
+	elemType := b.llvmType(elem)
+	n := b.builder.CreateSub(high, low, "n")
+	size := b.builder.CreateMul(n, b.elemSize(elem), "")
+	raw := b.callExternal("malloc", size)
+	src := b.builder.CreateGEP(elemType, data, []llvm.Value{low}, "")
+	i8Ptr := llvm.PointerType(b.context.Int8Type(), 0)
+	b.callExternal("memcpy", raw, b.builder.CreateBitCast(src, i8Ptr, ""), size)
+	buf := b.builder.CreateBitCast(raw, llvm.PointerType(elemType, 0), "data")
+
+	slice := b.sliceType(elem)
+	return value{b.buildSlice(slice, buf, n, n), slice}, nil
+}
+
+// sliceBounds returns the bounds of a slice as i64 offsets, where an
+// inclusive range selects up to and including its upper bound
+func (b *LLVMCodeBuilder) sliceBounds(rng *syntax.RangeExpr, length llvm.Value) (low, high llvm.Value, err error) {
+	i64 := b.context.Int64Type()
+	low = llvm.ConstInt(i64, 0, false)
+	high = length
+	if rng.Low != nil {
+		if low, err = b.generateOffset(rng.Low); err != nil {
+			return llvm.Value{}, llvm.Value{}, err
+		}
+	}
+	if rng.High != nil {
+		if high, err = b.generateOffset(rng.High); err != nil {
+			return llvm.Value{}, llvm.Value{}, err
+		}
+		if rng.Inclusive {
+			high = b.builder.CreateAdd(high, llvm.ConstInt(i64, 1, false), "")
+		}
+	}
+	return low, high, nil
+}
+
+// generateOffset generates an integer index widened to i64. Negative signed
+// indexes become large offsets, which fail the bounds checks.
+func (b *LLVMCodeBuilder) generateOffset(expr syntax.Expr) (llvm.Value, error) {
+	v, err := b.generateExprAs(expr, typeI64)
+	if err != nil {
+		return llvm.Value{}, err
+	}
+	if v.typ.Kind != KindInt {
+		return llvm.Value{}, b.errorAt(expr, "index must be an integer, got %s", v.typ)
+	}
+	i64 := b.context.Int64Type()
+	switch {
+	case v.typ.Bits == 64:
+		return v.val, nil
+	case v.typ.Signed:
+		return b.builder.CreateSExt(v.val, i64, "idx"), nil
+	default:
+		return b.builder.CreateZExt(v.val, i64, "idx"), nil
+	}
+}
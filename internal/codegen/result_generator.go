@@ -0,0 +1,128 @@
+package codegen
+
+import (
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Names of the built-in Result type and its variants
+const (
+	resultName = "Result"
+	resultOk   = "Ok"
+	resultErr  = "Err"
+)
+
+// declareUniverse creates the scope of built-in declarations enclosing every
+// package scope. It holds the generic Result<T, E>, declared as the enum
+// enum { Ok(T), Err(E) }, so Results are ordinary tagged unions with Ok as
+// tag 0 and Err as tag 1.
+func (b *LLVMCodeBuilder) declareUniverse() {
+	b.universe = newScope(nil)
+
+	param := func(name string) syntax.TypeExpr {
+		return &syntax.NamedType{Name: name}
+	}
+	decl := &syntax.TypeDecl{
+		Name:       resultName,
+		TypeParams: []string{"T", "E"},
+		Enum: &syntax.EnumType{Variants: []syntax.Variant{
+			{Name: resultOk, Types: []syntax.TypeExpr{param("T")}},
+			{Name: resultErr, Types: []syntax.TypeExpr{param("E")}},
+		}},
+	}
+
+	b.resultType = &Type{
+		Kind:    KindEnum,
+		Name:    resultName,
+		Symbol:  resultName,
+		Generic: &genericType{decl: decl, scope: b.universe},
+	}
+	b.universe.define(&symbol{kind: symbolType, name: resultName, typ: b.resultType, decl: decl})
+}
+
+// isResult reports whether t is an instantiation of Result
+func (b *LLVMCodeBuilder) isResult(t *Type) bool {
+	return t.Origin != nil && t.Origin == b.resultType
+}
+
+// generateResultCall constructs a Result with Ok(v) or Err(e). The other
+// type argument comes from the expected type, such as the result type of
+// the enclosing function.
+func (b *LLVMCodeBuilder) generateResultCall(expr *syntax.CallExpr, callee *syntax.Ident, want *Type) (value, error) {
+	sel := &syntax.SelectorExpr{
+		X:    &syntax.Ident{Name: resultName, Pos_: callee.Pos_},
+		Sel:  callee.Name,
+		Pos_: callee.Pos_,
+	}
+	return b.generateVariant(sel, b.resultType, expr.Args, want)
+}
+
+// generateHandleStmt lowers handle to a match on the Result variants; both
+// branches are required
+func (b *LLVMCodeBuilder) generateHandleStmt(stmt *syntax.HandleStmt) error {
+	subject, err := b.generateExpr(stmt.X)
+	if err != nil {
+		return err
+	}
+	if !b.isResult(subject.typ) {
+		return b.errorAt(stmt.X, "cannot handle non-Result type %s", subject.typ)
+	}
+	if stmt.Ok == nil {
+		return b.errorAt(stmt, "handle of %s is missing the Ok branch", subject.typ)
+	}
+	if stmt.Err == nil {
+		return b.errorAt(stmt, "handle of %s is missing the Err branch", subject.typ)
+	}
+
+	match := &syntax.MatchStmt{
+		X: stmt.X,
+		Arms: []syntax.MatchArm{
+			{Variant: resultOk, Bindings: []string{stmt.Ok.Name}, Body: stmt.Ok.Body, Pos_: stmt.Ok.Pos_},
+			{Variant: resultErr, Bindings: []string{stmt.Err.Name}, Body: stmt.Err.Body, Pos_: stmt.Err.Pos_},
+		},
+		Pos_: stmt.Pos_,
+	}
+	return b.generateMatch(match, subject)
+}
+
+// generateTryExpr lowers x? inside a function returning Result<U, E>: an Err
+// of x is returned from the function as is, otherwise the expression yields
+// the Ok value of x
+func (b *LLVMCodeBuilder) generateTryExpr(expr *syntax.TryExpr) (value, error) {
+	if b.fn.fn == nil {
+		return value{}, b.errorAt(expr, "? used outside of a function")
+	}
+	result := b.fn.fn.result
+	if !b.isResult(result) {
+		return value{}, b.errorAt(expr, "? used in function %s, which returns %s instead of a Result", b.fn.fn.name, result)
+	}
+
+	x, err := b.generateExpr(expr.X)
+	if err != nil {
+		return value{}, err
+	}
+	if !b.isResult(x.typ) {
+		return value{}, b.errorAt(expr, "? applied to non-Result type %s", x.typ)
+	}
+	if errType := x.typ.TypeArgs[1]; errType != result.TypeArgs[1] {
+		return value{}, b.errorAt(expr, "? cannot propagate error type %s from function %s, which returns %s", errType, b.fn.fn.name, result)
+	}
+
+	enumType := b.llvmType(x.typ)
+	tmp := b.createEntryAlloca(enumType, "try")
+	b.builder.CreateStore(x.val, tmp)
+	tag := b.builder.CreateLoad(b.context.Int32Type(), b.builder.CreateStructGEP(enumType, tmp, 0, "tag"), "tag")
+	isErr := b.builder.CreateICmp(llvm.IntEQ, tag, llvm.ConstInt(b.context.Int32Type(), 1, false), "is.err")
+
+	okBlock := b.context.AddBasicBlock(b.fn.value, "try.ok")
+	errBlock := b.context.InsertBasicBlock(okBlock, "try.err")
+	b.builder.CreateCondBr(isErr, errBlock, okBlock)
+
+	// The Err payload is rewrapped, since the Ok types may differ
+	b.builder.SetInsertPointAtEnd(errBlock)
+	errValue := b.loadPayload(x.typ, 1, tmp, 0)
+	b.builder.CreateRet(b.buildVariant(result, 1, []llvm.Value{errValue}))
+
+	b.builder.SetInsertPointAtEnd(okBlock)
+	return value{b.loadPayload(x.typ, 0, tmp, 0), x.typ.TypeArgs[0]}, nil
+}
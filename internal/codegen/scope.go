@@ -0,0 +1,145 @@
+package codegen
+
+import (
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// symbolKind classifies what a name refers to
+type symbolKind int
+
+const (
+	symbolVar     symbolKind = iota // local or global storage
+	symbolFunc                      // function declared in a package
+	symbolPackage                   // imported package
+	symbolType                      // declared type
+)
+
+// symbol is a named entity visible in a scope
+type symbol struct {
+	kind symbolKind
+	name string
+	typ  *Type
+	ptr  llvm.Value    // storage for variables
+	fn   *function     // for symbolFunc
+	pkg  *packageScope // for symbolPackage, and the declaring package otherwise
+	decl syntax.Node   // declaration site, for diagnostics
+
+	constant bool    // top-level definitions cannot be assigned
+	narrowed *symbol // optional variable a narrowed symbol views the value of
+	loops    int     // loop depth at which a narrowing holds
+}
+
+// isExported reports whether a top-level symbol is declared with export and
+// can be referred to from other packages
+func (s *symbol) isExported() bool {
+	switch d := s.decl.(type) {
+	case *syntax.FunDecl:
+		return d.Exported
+	case *syntax.VarDecl:
+		return d.Exported
+	case *syntax.TypeDecl:
+		return d.Exported
+	}
+	return false
+}
+
+// scope is a lexical scope; lookups walk up to the package scope
+type scope struct {
+	parent  *scope
+	symbols map[string]*symbol
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{
+		parent:  parent,
+		symbols: make(map[string]*symbol),
+	}
+}
+
+// lookup finds a symbol in this scope or any enclosing one
+func (s *scope) lookup(name string) *symbol {
+	for sc := s; sc != nil; sc = sc.parent {
+		if sym, ok := sc.symbols[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+// lookupLocal finds a symbol declared directly in this scope
+func (s *scope) lookupLocal(name string) *symbol {
+	return s.symbols[name]
+}
+
+func (s *scope) define(sym *symbol) {
+	s.bind(sym.name, sym)
+}
+
+// bind makes sym visible under the given name, which differs from the symbol's
+// own name for imported symbols
+func (s *scope) bind(name string, sym *symbol) {
+	s.symbols[name] = sym
+}
+
+// function is a Guayavita function lowered to an LLVM function. Generic
+// functions have no LLVM value themselves; each instantiation is a separate
+// function.
+type function struct {
+	name   string
+	decl   *syntax.FunDecl
+	pkg    *packageScope
+	value  llvm.Value
+	fnType llvm.Type
+	recv   *Type // receiver of methods taking self, passed by pointer
+	params []*Type
+	result *Type
+
+	scope     *scope               // scope the declaration resolves names in
+	instances map[string]*function // instantiations of a generic function
+	generic   *function            // generic function an instantiation was made from
+	typeArgs  []*Type              // type arguments of an instantiation
+	parent    *function            // function whose body instantiated or declared this one
+
+	closure  bool      // takes the environment of a closure as first parameter
+	captures []*symbol // variables whose addresses the environment holds, in order
+}
+
+// isGeneric reports whether fn has type parameters
+func (fn *function) isGeneric() bool {
+	return len(fn.decl.TypeParams) > 0
+}
+
+// funcState holds per-function generation state; it is swapped while a
+// nested function body is generated
+type funcState struct {
+	fn    *function
+	value llvm.Value // LLVM function being generated
+	scope *scope
+	loops []loop // enclosing loops, innermost last
+
+	// captured holds the names used by function literals in the body;
+	// locals with these names live on the heap
+	captured map[string]bool
+
+	// reassigned holds the names assigned by function literals of the
+	// enclosing declaration, which are never narrowed
+	reassigned map[string]bool
+}
+
+// loop holds the jump targets of break and continue in a loop
+type loop struct {
+	label string
+	exit  llvm.BasicBlock // target of break
+	next  llvm.BasicBlock // target of continue: the condition or the latch
+}
+
+// pushScope opens a nested lexical scope in the current function
+func (b *LLVMCodeBuilder) pushScope() {
+	b.fn.scope = newScope(b.fn.scope)
+}
+
+// popScope closes the innermost lexical scope of the current function
+func (b *LLVMCodeBuilder) popScope() {
+	b.fn.scope = b.fn.scope.parent
+}
@@ -0,0 +1,181 @@
+package codegen
+
+import (
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Slices [T*] are lowered to the triple {T* data, i64 len, i64 cap} and
+// passed by value. The elements live in memory from malloc and realloc of
+// the C library; append grows the capacity by doubling and returns the new
+// slice, which is written back as in xs = append(xs, v).
+
+// sliceType returns the slice type of elem. Slice types are interned so that
+// identity stays pointer equality.
+func (b *LLVMCodeBuilder) sliceType(elem *Type) *Type {
+	if b.slices == nil {
+		b.slices = make(map[*Type]*Type)
+	}
+	if slice, ok := b.slices[elem]; ok {
+		return slice
+	}
+	slice := &Type{
+		Kind:   KindSlice,
+		Name:   "[" + elem.Name + "*]",
+		Symbol: "[" + mangledTypeName(elem) + "*]",
+		Elem:   elem,
+	}
+	b.slices[elem] = slice
+	return slice
+}
+
+// elemSize returns the allocation size of a slice element as an i64
+func (b *LLVMCodeBuilder) elemSize(elem *Type) llvm.Value {
+	td := llvm.NewTargetData(b.module.DataLayout())
+	defer td.Dispose()
+	return llvm.ConstInt(b.context.Int64Type(), td.TypeAllocSize(b.llvmType(elem)), false)
+}
+
+// buildSlice assembles a slice value from its data pointer, length and
+// capacity
+func (b *LLVMCodeBuilder) buildSlice(slice *Type, data, length, capacity llvm.Value) llvm.Value {
+	agg := llvm.ConstNull(b.llvmType(slice))
+	agg = b.builder.CreateInsertValue(agg, data, 0, "")
+	agg = b.builder.CreateInsertValue(agg, length, 1, "")
+	return b.builder.CreateInsertValue(agg, capacity, 2, "slice")
+}
+
+// generateSliceLit generates an array literal where a slice is expected,
+// copying the elements to newly allocated memory. The empty literal is the
+// slice without storage.
+func (b *LLVMCodeBuilder) generateSliceLit(lit *syntax.ArrayLit, slice *Type) (value, error) {
+	if len(lit.Elements) == 0 {
+		return value{llvm.ConstNull(b.llvmType(slice)), slice}, nil
+	}
+
+	elems := make([]llvm.Value, 0, len(lit.Elements))
+	for _, elemExpr := range lit.Elements {
+		v, err := b.generateExprAs(elemExpr, slice.Elem)
+		if err != nil {
+			return value{}, err
+		}
+		if v, err = b.assignable(v, slice.Elem, elemExpr); err != nil {
+			return value{}, err
+		}
+		elems = append(elems, v.val)
+	}
+
+	i64 := b.context.Int64Type()
+	elemType := b.llvmType(slice.Elem)
+	n := llvm.ConstInt(i64, uint64(len(elems)), false)
+	raw := b.callExternal("malloc", b.builder.CreateMul(n, b.elemSize(slice.Elem), ""))
+	data := b.builder.CreateBitCast(raw, llvm.PointerType(elemType, 0), "data")
+	for i, v := range elems {
+		ptr := b.builder.CreateGEP(elemType, data, []llvm.Value{llvm.ConstInt(i64, uint64(i), false)}, "")
+		b.builder.CreateStore(v, ptr)
+	}
+	return value{b.buildSlice(slice, data, n, n), slice}, nil
+}
+
+// sliceElemAddr returns the address of the element of a slice selected by
+// expr, after checking the index against the slice length
+func (b *LLVMCodeBuilder) sliceElemAddr(expr *syntax.IndexExpr, slice value) (llvm.Value, error) {
+	idx, err := b.generateOffset(expr.Index)
+	if err != nil {
+		return llvm.Value{}, err
+	}
+	length := b.builder.CreateExtractValue(slice.val, 1, "len")
+	b.generateCheck(b.builder.CreateICmp(llvm.IntULT, idx, length, "inbounds"), expr, "index out of range for "+slice.typ.Name)
+	data := b.builder.CreateExtractValue(slice.val, 0, "data")
+	return b.builder.CreateGEP(b.llvmType(slice.typ.Elem), data, []llvm.Value{idx}, "elem"), nil
+}
+
+// generateSliceLoop lowers a for-in loop over the elements a slice has when
+// the loop starts
+func (b *LLVMCodeBuilder) generateSliceLoop(stmt *syntax.ForInStmt, slice value) error {
+	data := b.builder.CreateExtractValue(slice.val, 0, "data")
+	length := b.builder.CreateExtractValue(slice.val, 1, "len")
+	elemType := b.llvmType(slice.typ.Elem)
+	return b.generateIndexLoop(stmt, length, slice.typ.Elem, func(i llvm.Value) llvm.Value {
+		return b.builder.CreateGEP(elemType, data, []llvm.Value{i}, "elem")
+	})
+}
+
+// generateLenCall generates len(x), the number of elements of a slice or an
+// array, or the number of bytes of a string, as an i64
+func (b *LLVMCodeBuilder) generateLenCall(expr *syntax.CallExpr) (value, error) {
+	if len(expr.Args) != 1 {
+		return value{}, b.errorAt(expr, "len expects exactly 1 argument, got %d", len(expr.Args))
+	}
+	x, err := b.generateExpr(expr.Args[0])
+	if err != nil {
+		return value{}, err
+	}
+
+	switch x.typ.Kind {
+	case KindSlice:
+		return value{b.builder.CreateExtractValue(x.val, 1, "len"), typeI64}, nil
+	case KindArray:
+		return value{llvm.ConstInt(b.context.Int64Type(), uint64(x.typ.Len), false), typeI64}, nil
+	case KindString:
+		return value{b.callExternal("strlen", x.val), typeI64}, nil
+	}
+	return value{}, b.errorAt(expr.Args[0], "invalid argument: %s of type %s for len", exprName(expr.Args[0]), x.typ)
+}
+
+// generateAppendCall generates append(xs, v), which stores v after the last
+// element of xs and returns the longer slice. When xs is full its storage is
+// reallocated with twice the capacity, so slices sharing the old storage
+// must not be used afterwards.
+func (b *LLVMCodeBuilder) generateAppendCall(expr *syntax.CallExpr, want *Type) (value, error) {
+	if len(expr.Args) != 2 {
+		return value{}, b.errorAt(expr, "append expects exactly 2 arguments, got %d", len(expr.Args))
+	}
+	xs, err := b.generateExprAs(expr.Args[0], want)
+	if err != nil {
+		return value{}, err
+	}
+	if xs.typ.Kind != KindSlice {
+		return value{}, b.errorAt(expr.Args[0], "first argument to append must be a slice, got %s", xs.typ)
+	}
+	elem := xs.typ.Elem
+	v, err := b.generateExprAs(expr.Args[1], elem)
+	if err != nil {
+		return value{}, err
+	}
+	if v, err = b.assignable(v, elem, expr.Args[1]); err != nil {
+		return value{}, err
+	}
+
+	i64 := b.context.Int64Type()
+	elemPtrType := llvm.PointerType(b.llvmType(elem), 0)
+	data := b.builder.CreateExtractValue(xs.val, 0, "data")
+	length := b.builder.CreateExtractValue(xs.val, 1, "len")
+	capacity := b.builder.CreateExtractValue(xs.val, 2, "cap")
+
+	fullBlock := b.builder.GetInsertBlock()
+	growBlock := b.context.AddBasicBlock(b.fn.value, "append.grow")
+	storeBlock := b.context.AddBasicBlock(b.fn.value, "append.store")
+	full := b.builder.CreateICmp(llvm.IntEQ, length, capacity, "full")
+	b.builder.CreateCondBr(full, growBlock, storeBlock)
+
+	b.builder.SetInsertPointAtEnd(growBlock)
+	isEmpty := b.builder.CreateICmp(llvm.IntEQ, capacity, llvm.ConstInt(i64, 0, false), "")
+	doubled := b.builder.CreateMul(capacity, llvm.ConstInt(i64, 2, false), "")
+	grown := b.builder.CreateSelect(isEmpty, llvm.ConstInt(i64, 4, false), doubled, "newcap")
+	raw := b.builder.CreateBitCast(data, llvm.PointerType(b.context.Int8Type(), 0), "")
+	raw = b.callExternal("realloc", raw, b.builder.CreateMul(grown, b.elemSize(elem), ""))
+	grownData := b.builder.CreateBitCast(raw, elemPtrType, "newdata")
+	b.builder.CreateBr(storeBlock)
+
+	b.builder.SetInsertPointAtEnd(storeBlock)
+	dataPhi := b.builder.CreatePHI(elemPtrType, "data")
+	dataPhi.AddIncoming([]llvm.Value{data, grownData}, []llvm.BasicBlock{fullBlock, growBlock})
+	capPhi := b.builder.CreatePHI(i64, "cap")
+	capPhi.AddIncoming([]llvm.Value{capacity, grown}, []llvm.BasicBlock{fullBlock, growBlock})
+
+	ptr := b.builder.CreateGEP(b.llvmType(elem), dataPhi, []llvm.Value{length}, "elem")
+	b.builder.CreateStore(v.val, ptr)
+	next := b.builder.CreateAdd(length, llvm.ConstInt(i64, 1, false), "len")
+	return value{b.buildSlice(xs.typ, dataPhi, next, capPhi), xs.typ}, nil
+}
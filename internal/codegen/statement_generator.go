@@ -1,12 +1,20 @@
 package codegen
 
 import (
+	"strings"
+
 	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
 )
 
 // generateBlock generates LLVM IR for a block statement
 func (b *LLVMCodeBuilder) generateBlock(block *syntax.Block) error {
 	for _, stmt := range block.Stmts {
+		if b.isTerminated() {
+			// Code following a return is unreachable; give it its own block
+			dead := b.context.AddBasicBlock(b.fn.value, "dead")
+			b.builder.SetInsertPointAtEnd(dead)
+		}
 		if err := b.generateStmt(stmt); err != nil {
 			return err
 		}
@@ -14,26 +22,324 @@ func (b *LLVMCodeBuilder) generateBlock(block *syntax.Block) error {
 	return nil
 }
 
+// generateScopedBlock generates a block inside a new lexical scope
+func (b *LLVMCodeBuilder) generateScopedBlock(block *syntax.Block) error {
+	b.pushScope()
+	defer b.popScope()
+	return b.generateBlock(block)
+}
+
 // generateStmt generates LLVM IR for a statement
 func (b *LLVMCodeBuilder) generateStmt(stmt syntax.Stmt) error {
 	switch s := stmt.(type) {
 	case *syntax.ExprStmt:
-		_, err := b.generateExpr(s.X)
-		return err
+		v, err := b.generateExpr(s.X)
+		if err != nil {
+			return err
+		}
+		if b.isResult(v.typ) {
+			return b.errorAt(s, "%s returns %s, which is neither used nor handled", exprName(s.X), v.typ)
+		}
+		return nil
 	case *syntax.VarDecl:
 		return b.generateVarDecl(s)
+	case *syntax.AssignStmt:
+		return b.generateAssignStmt(s)
 	case *syntax.ReturnStmt:
-		if s.Result != nil {
-			value, err := b.generateExpr(s.Result)
-			if err != nil {
-				return err
-			}
-			b.builder.CreateRet(value)
-		} else {
-			b.builder.CreateRetVoid()
-		}
-		return nil
+		return b.generateReturnStmt(s)
+	case *syntax.Block:
+		return b.generateScopedBlock(s)
+	case *syntax.IfStmt:
+		return b.generateIfStmt(s)
+	case *syntax.WhileStmt:
+		return b.generateWhileStmt(s)
+	case *syntax.ForStmt:
+		return b.generateForStmt(s)
+	case *syntax.ForInStmt:
+		return b.generateForInStmt(s)
+	case *syntax.BranchStmt:
+		return b.generateBranchStmt(s)
+	case *syntax.MatchStmt:
+		return b.generateMatchStmt(s)
+	case *syntax.HandleStmt:
+		return b.generateHandleStmt(s)
 	default:
 		return b.errorAt(s, "unsupported statement type: %T", stmt)
 	}
 }
+
+// generateReturnStmt generates LLVM IR for a return statement
+func (b *LLVMCodeBuilder) generateReturnStmt(stmt *syntax.ReturnStmt) error {
+	result := b.fn.fn.result
+	if stmt.Result == nil {
+		if result.Kind != KindVoid {
+			return b.errorAt(stmt, "missing return value of type %s", result)
+		}
+		b.builder.CreateRetVoid()
+		return nil
+	}
+
+	value, err := b.generateExprAs(stmt.Result, result)
+	if err != nil {
+		return err
+	}
+	if result.Kind == KindVoid {
+		if value.typ.Kind != KindVoid {
+			return b.errorAt(stmt, "function %s does not return a value", b.fn.fn.name)
+		}
+		b.builder.CreateRetVoid()
+		return nil
+	}
+	if value, err = b.assignable(value, result, stmt.Result); err != nil {
+		return err
+	}
+	b.builder.CreateRet(value.val)
+	return nil
+}
+
+// generateAssignStmt stores into a variable, a field or an element. Compound
+// assignments load the target once and apply the arithmetic operator.
+func (b *LLVMCodeBuilder) generateAssignStmt(stmt *syntax.AssignStmt) error {
+	root := b.rootSymbol(stmt.Left)
+	if root != nil && root.constant {
+		return b.errorAt(stmt.Left, "cannot assign to %s: top-level definition %s is constant", exprName(stmt.Left), root.name)
+	}
+
+	if _, isIdent := stmt.Left.(*syntax.Ident); isIdent && stmt.Op == "=" && root.narrowed != nil {
+		return b.assignNarrowed(stmt, root)
+	}
+
+	ptr, typ, ok, err := b.generateAddr(stmt.Left)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return b.errorAt(stmt.Left, "cannot assign to %s", exprName(stmt.Left))
+	}
+
+	right, err := b.generateExprAs(stmt.Right, typ)
+	if err != nil {
+		return err
+	}
+
+	if stmt.Op != "=" {
+		if typ.Kind == KindOptional {
+			return b.optionalUseError(stmt.Left, typ)
+		}
+		op := &syntax.BinaryExpr{Left: stmt.Left, Op: strings.TrimSuffix(stmt.Op, "="), Right: stmt.Right, Pos_: stmt.Pos_}
+		if right.typ != typ && !(isShift(op.Op) && typ.Kind == KindInt) {
+			return b.errorAt(stmt, "mismatched types %s and %s for operator %s", typ, right.typ, stmt.Op)
+		}
+		apply := b.generateArithmetic
+		if isShift(op.Op) && typ.Kind == KindInt {
+			apply = b.generateShift
+		}
+		current := value{b.builder.CreateLoad(b.llvmType(typ), ptr, "cur"), typ}
+		if right, err = apply(op, current, right); err != nil {
+			return err
+		}
+	} else if right, err = b.assignable(right, typ, stmt.Right); err != nil {
+		return err
+	}
+
+	b.builder.CreateStore(right.val, ptr)
+	return nil
+}
+
+// generateCondition generates a condition expression, which must be a bool
+func (b *LLVMCodeBuilder) generateCondition(expr syntax.Expr) (value, error) {
+	cond, err := b.generateExprAs(expr, typeBool)
+	if err != nil {
+		return value{}, err
+	}
+	if cond.typ != typeBool {
+		return value{}, b.errorAt(expr, "non-bool %s used as condition", cond.typ)
+	}
+	return cond, nil
+}
+
+// generateIfStmt generates LLVM IR for an if statement with optional else branch
+func (b *LLVMCodeBuilder) generateIfStmt(stmt *syntax.IfStmt) error {
+	cond, err := b.generateCondition(stmt.Cond)
+	if err != nil {
+		return err
+	}
+
+	thenBlock := b.context.AddBasicBlock(b.fn.value, "if.then")
+	mergeBlock := b.context.AddBasicBlock(b.fn.value, "if.end")
+	elseBlock := mergeBlock
+	if stmt.Else != nil {
+		elseBlock = b.context.InsertBasicBlock(mergeBlock, "if.else")
+	}
+	b.builder.CreateCondBr(cond.val, thenBlock, elseBlock)
+
+	// Optionals checked against none are narrowed in the branches
+	present, absent := noneChecks(stmt.Cond)
+
+	b.builder.SetInsertPointAtEnd(thenBlock)
+	if err := b.generateNarrowed(stmt.Body, present); err != nil {
+		return err
+	}
+	thenExits := b.isTerminated()
+	if !thenExits {
+		b.builder.CreateBr(mergeBlock)
+	}
+
+	elseExits := false
+	if stmt.Else != nil {
+		b.builder.SetInsertPointAtEnd(elseBlock)
+		if err := b.generateNarrowed(stmt.Else, absent); err != nil {
+			return err
+		}
+		elseExits = b.isTerminated()
+		if !elseExits {
+			b.builder.CreateBr(mergeBlock)
+		}
+	}
+
+	// When a branch always leaves the block, the rest of the block is only
+	// reached through the other one
+	b.builder.SetInsertPointAtEnd(mergeBlock)
+	if thenExits {
+		b.narrow(absent)
+	}
+	if elseExits {
+		b.narrow(present)
+	}
+	return nil
+}
+
+// generateNarrowed generates a branch statement in a scope narrowing the named
+// optionals
+func (b *LLVMCodeBuilder) generateNarrowed(stmt syntax.Stmt, names []string) error {
+	b.pushScope()
+	defer b.popScope()
+	b.narrow(names)
+	return b.generateStmt(stmt)
+}
+
+// generateWhileStmt generates LLVM IR for a while loop
+func (b *LLVMCodeBuilder) generateWhileStmt(stmt *syntax.WhileStmt) error {
+	condBlock := b.context.AddBasicBlock(b.fn.value, "while.cond")
+	bodyBlock := b.context.AddBasicBlock(b.fn.value, "while.body")
+	exitBlock := b.context.AddBasicBlock(b.fn.value, "while.end")
+
+	b.builder.CreateBr(condBlock)
+	b.builder.SetInsertPointAtEnd(condBlock)
+	cond, err := b.generateCondition(stmt.Cond)
+	if err != nil {
+		return err
+	}
+	b.builder.CreateCondBr(cond.val, bodyBlock, exitBlock)
+
+	b.builder.SetInsertPointAtEnd(bodyBlock)
+	present, _ := noneChecks(stmt.Cond)
+	if err := b.generateLoopBody(stmt, stmt.Label, stmt.Body, present, exitBlock, condBlock); err != nil {
+		return err
+	}
+	if !b.isTerminated() {
+		b.builder.CreateBr(condBlock)
+	}
+
+	b.builder.SetInsertPointAtEnd(exitBlock)
+	return nil
+}
+
+// generateForStmt generates LLVM IR for a C-style for loop. The init clause
+// runs once in a scope enclosing the loop; the condition is tested in the
+// header block and the post clause runs in the latch block after each
+// iteration of the body.
+func (b *LLVMCodeBuilder) generateForStmt(stmt *syntax.ForStmt) error {
+	b.pushScope()
+	defer b.popScope()
+
+	if stmt.Init != nil {
+		if err := b.generateStmt(stmt.Init); err != nil {
+			return err
+		}
+	}
+	if _, ok := stmt.Post.(*syntax.VarDecl); ok {
+		return b.errorAt(stmt.Post, "cannot declare variables in the post statement of a for loop")
+	}
+
+	condBlock := b.context.AddBasicBlock(b.fn.value, "for.cond")
+	bodyBlock := b.context.AddBasicBlock(b.fn.value, "for.body")
+	postBlock := b.context.AddBasicBlock(b.fn.value, "for.post")
+	exitBlock := b.context.AddBasicBlock(b.fn.value, "for.end")
+
+	b.builder.CreateBr(condBlock)
+	b.builder.SetInsertPointAtEnd(condBlock)
+	var present []string
+	if stmt.Cond != nil {
+		cond, err := b.generateCondition(stmt.Cond)
+		if err != nil {
+			return err
+		}
+		b.builder.CreateCondBr(cond.val, bodyBlock, exitBlock)
+		present, _ = noneChecks(stmt.Cond)
+	} else {
+		b.builder.CreateBr(bodyBlock)
+	}
+
+	b.builder.SetInsertPointAtEnd(bodyBlock)
+	if err := b.generateLoopBody(stmt, stmt.Label, stmt.Body, present, exitBlock, postBlock); err != nil {
+		return err
+	}
+	if !b.isTerminated() {
+		b.builder.CreateBr(postBlock)
+	}
+
+	b.builder.SetInsertPointAtEnd(postBlock)
+	if stmt.Post != nil {
+		if err := b.generateStmt(stmt.Post); err != nil {
+			return err
+		}
+	}
+	b.builder.CreateBr(condBlock)
+
+	b.builder.SetInsertPointAtEnd(exitBlock)
+	return nil
+}
+
+// generateLoopBody generates the body of a loop with the optionals proven
+// present by its condition narrowed. break jumps to exit and continue to next.
+func (b *LLVMCodeBuilder) generateLoopBody(stmt syntax.Stmt, label string, body *syntax.Block, present []string, exit, next llvm.BasicBlock) error {
+	if label != "" {
+		for _, outer := range b.fn.loops {
+			if outer.label == label {
+				return b.errorAt(stmt, "label %s already defined by an enclosing loop", label)
+			}
+		}
+	}
+
+	b.fn.loops = append(b.fn.loops, loop{label: label, exit: exit, next: next})
+	defer func() { b.fn.loops = b.fn.loops[:len(b.fn.loops)-1] }()
+	return b.generateNarrowed(body, present)
+}
+
+// generateBranchStmt generates break and continue, which jump out of the
+// innermost loop or of the loop with the given label
+func (b *LLVMCodeBuilder) generateBranchStmt(stmt *syntax.BranchStmt) error {
+	keyword := strings.ToLower(string(stmt.Tok))
+	if len(b.fn.loops) == 0 {
+		return b.errorAt(stmt, "%s is not in a loop", keyword)
+	}
+
+	target := b.fn.loops[len(b.fn.loops)-1]
+	if stmt.Label != "" {
+		found := false
+		for i := len(b.fn.loops) - 1; i >= 0 && !found; i-- {
+			target, found = b.fn.loops[i], b.fn.loops[i].label == stmt.Label
+		}
+		if !found {
+			return b.errorAt(stmt, "%s label %s is not defined by an enclosing loop", keyword, stmt.Label)
+		}
+	}
+
+	if stmt.Tok == syntax.BREAK {
+		b.builder.CreateBr(target.exit)
+	} else {
+		b.builder.CreateBr(target.next)
+	}
+	return nil
+}
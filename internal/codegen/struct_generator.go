@@ -0,0 +1,396 @@
+package codegen
+
+import (
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// declareTypes registers every type declared in the package. Names are bound
+// first so that field types may refer to types declared later, followed by
+// the interfaces implemented by impl blocks. Generic types are resolved per
+// instantiation.
+func (b *LLVMCodeBuilder) declareTypes(ps *packageScope) error {
+	type pending struct {
+		typ  *Type
+		decl *syntax.TypeDecl
+		sc   *scope
+	}
+	var decls []pending
+
+	for _, file := range ps.pkg.Files {
+		for _, decl := range file.Decls {
+			d, ok := decl.(*syntax.TypeDecl)
+			if !ok {
+				continue
+			}
+			if ps.scope.lookupLocal(d.Name) != nil {
+				return b.errorAt(d, "%s redeclared in package %s", d.Name, ps.path)
+			}
+			typ := &Type{Kind: KindStruct, Name: d.Name, Symbol: ps.mangle(d.Name)}
+			switch {
+			case d.Enum != nil:
+				typ.Kind = KindEnum
+			case d.Interface != nil:
+				typ.Kind = KindInterface
+				if len(d.TypeParams) > 0 {
+					return b.errorAt(d, "interface %s cannot have type parameters", d.Name)
+				}
+			}
+			if len(d.TypeParams) > 0 {
+				typ.Generic = &genericType{decl: d, pkg: ps, scope: ps.files[file]}
+			}
+			ps.scope.define(&symbol{kind: symbolType, name: d.Name, typ: typ, pkg: ps, decl: d})
+			decls = append(decls, pending{typ, d, ps.files[file]})
+		}
+	}
+
+	if err := b.declareImplements(ps); err != nil {
+		return err
+	}
+
+	for _, p := range decls {
+		if p.typ.Generic != nil {
+			if err := b.checkBoundTypes(p.decl.Bounds, p.sc); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := b.resolveTypeBody(p.typ, p.decl, p.sc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveTypeBody resolves the members of a struct, enum or interface
+// declaration and rejects types that contain themselves by value
+func (b *LLVMCodeBuilder) resolveTypeBody(typ *Type, decl *syntax.TypeDecl, sc *scope) error {
+	var err error
+	switch {
+	case decl.Enum != nil:
+		err = b.resolveEnumVariants(typ, decl.Enum, sc)
+	case decl.Interface != nil:
+		err = b.resolveInterfaceMethods(typ, decl.Interface, sc)
+	default:
+		err = b.resolveStructFields(typ, decl.Struct, sc)
+	}
+	if err != nil {
+		return err
+	}
+
+	if containsType(typ, typ, map[*Type]bool{}) {
+		return b.errorAt(decl, "invalid recursive type %s", typ)
+	}
+	return nil
+}
+
+// resolveStructFields resolves the field types of a struct declaration
+func (b *LLVMCodeBuilder) resolveStructFields(typ *Type, st *syntax.StructType, sc *scope) error {
+	for i := range st.Fields {
+		field := &st.Fields[i]
+		if typ.FieldIndex(field.Name) >= 0 {
+			return b.errorAt(field, "duplicate field %s in struct %s", field.Name, typ.Name)
+		}
+		fieldType, err := b.resolveType(field.Type, sc)
+		if err != nil {
+			return err
+		}
+		if fieldType.Kind == KindVoid {
+			return b.errorAt(field, "field %s cannot have type %s", field.Name, fieldType)
+		}
+		typ.Fields = append(typ.Fields, StructField{Name: field.Name, Type: fieldType})
+	}
+	return nil
+}
+
+// containsType reports whether t holds target by value, directly or through
+// nested struct fields and enum payloads
+func containsType(t, target *Type, seen map[*Type]bool) bool {
+	members := make([]*Type, 0, len(t.Fields))
+	for _, field := range t.Fields {
+		members = append(members, field.Type)
+	}
+	for _, variant := range t.Variants {
+		members = append(members, variant.Payload...)
+	}
+
+	for _, member := range members {
+		if member == target {
+			return true
+		}
+		if (member.Kind == KindStruct || member.Kind == KindEnum) && !seen[member] {
+			seen[member] = true
+			if containsType(member, target, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// structType returns the named LLVM struct type of a struct, creating it on
+// first use
+func (b *LLVMCodeBuilder) structType(t *Type) llvm.Type {
+	if b.structTypes == nil {
+		b.structTypes = make(map[*Type]llvm.Type)
+	}
+	if st, ok := b.structTypes[t]; ok {
+		return st
+	}
+
+	st := b.context.StructCreateNamed(t.Symbol)
+	b.structTypes[t] = st
+
+	elements := make([]llvm.Type, 0, len(t.Fields))
+	for _, field := range t.Fields {
+		elements = append(elements, b.llvmType(field.Type))
+	}
+	st.StructSetBody(elements, false)
+
+	return st
+}
+
+// generateStructLit builds a struct value by storing every field into a
+// temporary through GEP and loading the result. The type arguments of a
+// generic struct come from the expected type or are inferred from the fields.
+func (b *LLVMCodeBuilder) generateStructLit(lit *syntax.StructLit, want *Type) (value, error) {
+	typ, err := b.lookupNamedType(lit.Type)
+	if err != nil {
+		return value{}, err
+	}
+	if typ == nil {
+		return value{}, b.errorAt(lit.Type, "unknown type: %s", exprName(lit.Type))
+	}
+	if typ.Kind != KindStruct {
+		return value{}, b.errorAt(lit, "invalid struct literal of non-struct type %s", typ)
+	}
+
+	// Field values generated while inferring type arguments
+	var inferred map[string]value
+	if typ.Generic != nil {
+		if want != nil && want.Origin == typ {
+			typ = want
+		} else if typ, inferred, err = b.inferStructLit(lit, typ); err != nil {
+			return value{}, err
+		}
+	}
+
+	structType := b.llvmType(typ)
+	tmp := b.createEntryAlloca(structType, typ.Name)
+
+	initialized := make(map[string]bool, len(lit.Fields))
+	for i := range lit.Fields {
+		field := &lit.Fields[i]
+		idx := typ.FieldIndex(field.Name)
+		if idx < 0 {
+			return value{}, b.errorAt(field, "unknown field %s in struct literal of type %s", field.Name, typ)
+		}
+		if initialized[field.Name] {
+			return value{}, b.errorAt(field, "duplicate field %s in struct literal", field.Name)
+		}
+		initialized[field.Name] = true
+
+		fieldType := typ.Fields[idx].Type
+		v, ok := inferred[field.Name]
+		if !ok {
+			if v, err = b.generateExprAs(field.Value, fieldType); err != nil {
+				return value{}, err
+			}
+		}
+		if v, err = b.assignable(v, fieldType, field.Value); err != nil {
+			return value{}, err
+		}
+		ptr := b.builder.CreateStructGEP(structType, tmp, idx, field.Name)
+		b.builder.CreateStore(v.val, ptr)
+	}
+
+	for _, field := range typ.Fields {
+		if !initialized[field.Name] {
+			return value{}, b.errorAt(lit, "missing field %s in struct literal of type %s", field.Name, typ)
+		}
+	}
+
+	return value{b.builder.CreateLoad(structType, tmp, typ.Name), typ}, nil
+}
+
+// inferStructLit infers the type arguments of a literal of a generic struct
+// from its field values, returning the instantiation and the generated values
+func (b *LLVMCodeBuilder) inferStructLit(lit *syntax.StructLit, generic *Type) (*Type, map[string]value, error) {
+	g := generic.Generic
+	decls := make([]syntax.TypeExpr, 0, len(lit.Fields))
+	args := make([]syntax.Expr, 0, len(lit.Fields))
+	for i := range lit.Fields {
+		field := &lit.Fields[i]
+		var decl *syntax.Field
+		for j := range g.decl.Struct.Fields {
+			if g.decl.Struct.Fields[j].Name == field.Name {
+				decl = &g.decl.Struct.Fields[j]
+			}
+		}
+		if decl == nil {
+			return nil, nil, b.errorAt(field, "unknown field %s in struct literal of type %s", field.Name, generic)
+		}
+		decls = append(decls, decl.Type)
+		args = append(args, field.Value)
+	}
+
+	values, typeArgs, err := b.inferTypeArgs(generic.Name, g.decl.TypeParams, g.scope, decls, args, nil, nil, lit)
+	if err != nil {
+		return nil, nil, err
+	}
+	typ, err := b.instantiateType(generic, typeArgs, lit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inferred := make(map[string]value, len(values))
+	for i, v := range values {
+		inferred[lit.Fields[i].Name] = v
+	}
+	return typ, inferred, nil
+}
+
+// lookupNamedType returns the declared type named by expr (Name or
+// pkg.Name), or nil when expr does not name a type
+func (b *LLVMCodeBuilder) lookupNamedType(expr syntax.Expr) (*Type, error) {
+	var sym *symbol
+	switch e := expr.(type) {
+	case *syntax.Ident:
+		sym = b.fn.scope.lookup(e.Name)
+	case *syntax.SelectorExpr:
+		qualified, ok, err := b.lookupQualified(e)
+		if err != nil || !ok {
+			return nil, err
+		}
+		sym = qualified
+	}
+	if sym == nil || sym.kind != symbolType {
+		return nil, nil
+	}
+	return sym.typ, nil
+}
+
+// generateAddr returns the address of an addressable expression: a variable,
+// a field of an addressable struct, an element of an addressable array or of
+// a slice variable. ok is false for other expressions.
+func (b *LLVMCodeBuilder) generateAddr(expr syntax.Expr) (ptr llvm.Value, typ *Type, ok bool, err error) {
+	switch e := expr.(type) {
+	case *syntax.Ident:
+		sym := b.fn.scope.lookup(e.Name)
+		if sym != nil && sym.kind == symbolVar {
+			return sym.ptr, sym.typ, true, nil
+		}
+	case *syntax.SelectorExpr:
+		if sym, qualified, err := b.lookupQualified(e); qualified {
+			if err != nil || sym.kind != symbolVar {
+				return llvm.Value{}, nil, false, err
+			}
+			return sym.ptr, sym.typ, true, nil
+		}
+
+		base, baseType, ok, err := b.generateAddr(e.X)
+		if err != nil || !ok {
+			return llvm.Value{}, nil, false, err
+		}
+		idx, err := b.fieldIndex(e, baseType)
+		if err != nil {
+			return llvm.Value{}, nil, false, err
+		}
+		ptr := b.builder.CreateStructGEP(b.llvmType(baseType), base, idx, e.Sel)
+		return ptr, baseType.Fields[idx].Type, true, nil
+	case *syntax.IndexExpr:
+		if _, isRange := e.Index.(*syntax.RangeExpr); isRange {
+			break
+		}
+		base, baseType, ok, err := b.generateAddr(e.X)
+		if err != nil || !ok {
+			return llvm.Value{}, nil, false, err
+		}
+		var ptr llvm.Value
+		switch baseType.Kind {
+		case KindArray:
+			ptr, err = b.arrayElemAddr(e, baseType, base)
+		case KindSlice:
+			slice := value{b.builder.CreateLoad(b.llvmType(baseType), base, "slice"), baseType}
+			ptr, err = b.sliceElemAddr(e, slice)
+		default:
+			return llvm.Value{}, nil, false, nil
+		}
+		if err != nil {
+			return llvm.Value{}, nil, false, err
+		}
+		return ptr, baseType.Elem, true, nil
+	}
+	return llvm.Value{}, nil, false, nil
+}
+
+// rootSymbol returns the variable an addressable expression is part of, or
+// nil
+func (b *LLVMCodeBuilder) rootSymbol(expr syntax.Expr) *symbol {
+	switch e := expr.(type) {
+	case *syntax.Ident:
+		return b.fn.scope.lookup(e.Name)
+	case *syntax.SelectorExpr:
+		if sym, qualified, err := b.lookupQualified(e); qualified {
+			if err != nil {
+				return nil
+			}
+			return sym
+		}
+		return b.rootSymbol(e.X)
+	case *syntax.IndexExpr:
+		return b.rootSymbol(e.X)
+	}
+	return nil
+}
+
+// fieldIndex looks up the field selected by expr in a struct type
+func (b *LLVMCodeBuilder) fieldIndex(expr *syntax.SelectorExpr, typ *Type) (int, error) {
+	if typ.Kind != KindStruct {
+		return -1, b.errorAt(expr, "%s.%s undefined (type %s has no field %s)", exprName(expr.X), expr.Sel, typ, expr.Sel)
+	}
+	idx := typ.FieldIndex(expr.Sel)
+	if idx < 0 {
+		return -1, b.errorAt(expr, "%s.%s undefined (type %s has no field %s)", exprName(expr.X), expr.Sel, typ, expr.Sel)
+	}
+	return idx, nil
+}
+
+// generateFieldAccess reads a struct field. Addressable structs are read with
+// a GEP and a load of the single field; temporaries use extractvalue.
+func (b *LLVMCodeBuilder) generateFieldAccess(expr *syntax.SelectorExpr) (value, error) {
+	ptr, typ, ok, err := b.generateAddr(expr)
+	if err != nil {
+		return value{}, err
+	}
+	if ok {
+		return value{b.builder.CreateLoad(b.llvmType(typ), ptr, expr.Sel), typ}, nil
+	}
+
+	base, err := b.generateExpr(expr.X)
+	if err != nil {
+		return value{}, err
+	}
+	idx, err := b.fieldIndex(expr, base.typ)
+	if err != nil {
+		return value{}, err
+	}
+	return value{b.builder.CreateExtractValue(base.val, idx, expr.Sel), base.typ.Fields[idx].Type}, nil
+}
+
+// exprName renders a short name of an expression for diagnostics
+func exprName(expr syntax.Expr) string {
+	switch e := expr.(type) {
+	case *syntax.Ident:
+		return e.Name
+	case *syntax.SelectorExpr:
+		return exprName(e.X) + "." + e.Sel
+	case *syntax.CallExpr:
+		return exprName(e.Fun) + "(...)"
+	case *syntax.IndexExpr:
+		return exprName(e.X) + "[...]"
+	default:
+		return "expression"
+	}
+}
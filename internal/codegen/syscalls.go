@@ -71,7 +71,6 @@ func syscall(target string, context llvm.Context, builder llvm.Builder, module l
 	default:
 		panic("unhandled default case")
 	}
-	return nil, nil
 }
 
 func parseTargetTriple(target string) TargetSystem {
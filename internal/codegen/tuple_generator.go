@@ -0,0 +1,119 @@
+package codegen
+
+import (
+	"strings"
+
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// Tuples are lowered to anonymous LLVM structs and passed and returned by
+// value. They are built with (a, b) and taken apart with def (x, y) = t.
+
+// tupleType returns the tuple type of the given element types. Tuple types
+// are interned so that identity stays pointer equality.
+func (b *LLVMCodeBuilder) tupleType(elems []*Type) *Type {
+	names := make([]string, 0, len(elems))
+	keys := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		names = append(names, elem.String())
+		keys = append(keys, mangledTypeName(elem))
+	}
+	key := "(" + strings.Join(keys, ",") + ")"
+
+	if b.tuples == nil {
+		b.tuples = make(map[string]*Type)
+	}
+	if tuple, ok := b.tuples[key]; ok {
+		return tuple
+	}
+	tuple := &Type{
+		Kind:   KindTuple,
+		Name:   "(" + strings.Join(names, ", ") + ")",
+		Symbol: key,
+		Elems:  elems,
+	}
+	b.tuples[key] = tuple
+	return tuple
+}
+
+// generateTupleExpr builds a tuple. When a tuple of the same length is
+// expected, each element is generated and converted as its element type.
+func (b *LLVMCodeBuilder) generateTupleExpr(expr *syntax.TupleExpr, want *Type) (value, error) {
+	if want != nil && (want.Kind != KindTuple || len(want.Elems) != len(expr.Elems)) {
+		want = nil
+	}
+
+	elems := make([]value, 0, len(expr.Elems))
+	types := make([]*Type, 0, len(expr.Elems))
+	for i, elemExpr := range expr.Elems {
+		var hint *Type
+		if want != nil {
+			hint = want.Elems[i]
+		}
+		elem, err := b.generateExprAs(elemExpr, hint)
+		if err != nil {
+			return value{}, err
+		}
+		if hint != nil {
+			if elem, err = b.assignable(elem, hint, elemExpr); err != nil {
+				return value{}, err
+			}
+		}
+		if elem.typ.Kind == KindVoid {
+			return value{}, b.errorAt(elemExpr, "tuple element cannot have type %s", elem.typ)
+		}
+		elems = append(elems, elem)
+		types = append(types, elem.typ)
+	}
+
+	tuple := b.tupleType(types)
+	agg := llvm.ConstNull(b.llvmType(tuple))
+	for i, elem := range elems {
+		agg = b.builder.CreateInsertValue(agg, elem.val, i, "")
+	}
+	return value{agg, tuple}, nil
+}
+
+// generateDestructuring declares one variable per element of a tuple,
+// def (q, r) = e. Elements named _ are discarded.
+func (b *LLVMCodeBuilder) generateDestructuring(decl *syntax.VarDecl, declared *Type, declScope *scope) error {
+	seen := make(map[string]bool, len(decl.Names))
+	for _, name := range decl.Names {
+		if name == "_" {
+			continue
+		}
+		if seen[name] {
+			return b.errorAt(decl, "%s repeated in destructuring", name)
+		}
+		seen[name] = true
+		if declScope.lookupLocal(name) != nil {
+			return b.errorAt(decl, "%s redeclared in this block", name)
+		}
+	}
+
+	init, err := b.generateExprAs(decl.Init, declared)
+	if err != nil {
+		return err
+	}
+	if declared != nil {
+		if init, err = b.assignable(init, declared, decl.Init); err != nil {
+			return err
+		}
+	}
+	if init.typ.Kind != KindTuple {
+		return b.errorAt(decl.Init, "cannot destructure non-tuple type %s", init.typ)
+	}
+	if len(init.typ.Elems) != len(decl.Names) {
+		return b.errorAt(decl, "cannot destructure %s into %d names", init.typ, len(decl.Names))
+	}
+
+	for i, name := range decl.Names {
+		if name == "_" {
+			continue
+		}
+		elem := value{b.builder.CreateExtractValue(init.val, i, name), init.typ.Elems[i]}
+		b.defineVar(decl, name, elem, declScope)
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package codegen
+
+import (
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+	"tinygo.org/x/go-llvm"
+)
+
+// TypeKind classifies Guayavita types by how they are lowered to LLVM
+type TypeKind int
+
+const (
+	KindVoid TypeKind = iota
+	KindBool
+	KindInt
+	KindFloat
+	KindString
+	KindStruct
+	KindEnum
+	KindOptional
+	KindTuple
+	KindArray
+	KindSlice
+	KindFunc
+	KindInterface
+)
+
+// Type describes a Guayavita type. Primitive types are singletons, so two
+// types are identical when their pointers are equal.
+type Type struct {
+	Kind     TypeKind
+	Name     string
+	Bits     int                  // width for integer and float types
+	Signed   bool                 // signedness for integer types
+	Fields   []StructField        // members of struct types
+	Variants []EnumVariant        // variants of enum types, indexed by tag
+	Methods  map[string]*function // methods declared in impl blocks
+	Sigs     []Signature          // methods of interface types, in vtable order
+	Ifaces   []*Type              // interfaces the type implements
+	Elem     *Type                // element type of optional, array and slice types
+	Elems    []*Type              // element types of tuples
+	Len      int                  // length of array types
+	Params   []*Type              // parameter types of function types
+	Result   *Type                // result type of function types
+	Symbol   string               // LLVM name of named types
+
+	Generic  *genericType // declaration of a generic type, which has no layout
+	Origin   *Type        // generic type this type instantiates
+	TypeArgs []*Type      // type arguments of an instantiation
+}
+
+// StructField is a named member of a struct type
+type StructField struct {
+	Name string
+	Type *Type
+}
+
+// EnumVariant is a variant of an enum type with its payload types
+type EnumVariant struct {
+	Name    string
+	Payload []*Type
+}
+
+// Signature is a method of an interface type; its index is the slot of the
+// method in the vtables of the interface
+type Signature struct {
+	Name string
+	Type *Type // function type of the method without its receiver
+}
+
+// FieldIndex returns the position of the named field, or -1
+func (t *Type) FieldIndex(name string) int {
+	for i, field := range t.Fields {
+		if field.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SigIndex returns the vtable slot of the named interface method, or -1
+func (t *Type) SigIndex(name string) int {
+	for i, sig := range t.Sigs {
+		if sig.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// VariantIndex returns the tag of the named variant, or -1
+func (t *Type) VariantIndex(name string) int {
+	for i, variant := range t.Variants {
+		if variant.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *Type) String() string {
+	return t.Name
+}
+
+// IsNumeric reports whether arithmetic operators apply to the type
+func (t *Type) IsNumeric() bool {
+	return t.Kind == KindInt || t.Kind == KindFloat
+}
+
+var (
+	typeNone   = &Type{Kind: KindVoid, Name: "none"}
+	typeBool   = &Type{Kind: KindBool, Name: "bool", Bits: 1}
+	typeI8     = &Type{Kind: KindInt, Name: "i8", Bits: 8, Signed: true}
+	typeI32    = &Type{Kind: KindInt, Name: "i32", Bits: 32, Signed: true}
+	typeI64    = &Type{Kind: KindInt, Name: "i64", Bits: 64, Signed: true}
+	typeU8     = &Type{Kind: KindInt, Name: "u8", Bits: 8}
+	typeU16    = &Type{Kind: KindInt, Name: "u16", Bits: 16}
+	typeU32    = &Type{Kind: KindInt, Name: "u32", Bits: 32}
+	typeU64    = &Type{Kind: KindInt, Name: "u64", Bits: 64}
+	typeF32    = &Type{Kind: KindFloat, Name: "f32", Bits: 32}
+	typeF64    = &Type{Kind: KindFloat, Name: "f64", Bits: 64}
+	typeByte   = &Type{Kind: KindInt, Name: "byte", Bits: 8}
+	typeRune   = &Type{Kind: KindInt, Name: "rune", Bits: 32, Signed: true}
+	typeString = &Type{Kind: KindString, Name: "string"}
+)
+
+// primitiveTypes maps the primitive type names of the grammar to their types
+var primitiveTypes = map[string]*Type{
+	"none":   typeNone,
+	"bool":   typeBool,
+	"i8":     typeI8,
+	"i32":    typeI32,
+	"i64":    typeI64,
+	"u8":     typeU8,
+	"u16":    typeU16,
+	"u32":    typeU32,
+	"u64":    typeU64,
+	"f32":    typeF32,
+	"f64":    typeF64,
+	"byte":   typeByte,
+	"rune":   typeRune,
+	"string": typeString,
+}
+
+// resolveType resolves a type written in the source, looking up declared
+// and imported (pkg.Name) types in sc and instantiating generic types
+func (b *LLVMCodeBuilder) resolveType(t syntax.TypeExpr, sc *scope) (*Type, error) {
+	if opt, ok := t.(*syntax.OptionalType); ok {
+		elem, err := b.resolveType(opt.Elem, sc)
+		if err != nil {
+			return nil, err
+		}
+		if elem.Kind == KindVoid || elem.Kind == KindOptional {
+			return nil, b.errorAt(t, "invalid optional type %s", t)
+		}
+		return b.optionalType(elem), nil
+	}
+
+	if array, ok := t.(*syntax.ArrayType); ok {
+		elem, err := b.resolveType(array.Elem, sc)
+		if err != nil {
+			return nil, err
+		}
+		if !isPrimitive(elem) {
+			return nil, b.errorAt(t, "array element type must be a primitive type, got %s", elem)
+		}
+		return b.arrayType(elem, array.Len), nil
+	}
+
+	if slice, ok := t.(*syntax.SliceType); ok {
+		elem, err := b.resolveType(slice.Elem, sc)
+		if err != nil {
+			return nil, err
+		}
+		if elem.Kind == KindVoid {
+			return nil, b.errorAt(t, "invalid slice type %s", t)
+		}
+		return b.sliceType(elem), nil
+	}
+
+	if fn, ok := t.(*syntax.FuncType); ok {
+		params := make([]*Type, 0, len(fn.Params))
+		for _, paramExpr := range fn.Params {
+			param, err := b.resolveType(paramExpr, sc)
+			if err != nil {
+				return nil, err
+			}
+			if param.Kind == KindVoid {
+				return nil, b.errorAt(paramExpr, "parameter cannot have type %s", param)
+			}
+			params = append(params, param)
+		}
+		result, err := b.resolveType(fn.Result, sc)
+		if err != nil {
+			return nil, err
+		}
+		return b.funcType(params, result), nil
+	}
+
+	if tuple, ok := t.(*syntax.TupleType); ok {
+		elems := make([]*Type, 0, len(tuple.Elems))
+		for _, elemExpr := range tuple.Elems {
+			elem, err := b.resolveType(elemExpr, sc)
+			if err != nil {
+				return nil, err
+			}
+			if elem.Kind == KindVoid {
+				return nil, b.errorAt(elemExpr, "tuple element cannot have type %s", elem)
+			}
+			elems = append(elems, elem)
+		}
+		return b.tupleType(elems), nil
+	}
+
+	named, ok := t.(*syntax.NamedType)
+	if !ok {
+		return nil, b.errorAt(t, "unsupported type %s", t)
+	}
+
+	if named.Pkg == "" {
+		if prim, ok := primitiveTypes[named.Name]; ok {
+			if len(named.Args) > 0 {
+				return nil, b.errorAt(t, "type %s is not generic", named.Name)
+			}
+			return prim, nil
+		}
+	}
+
+	var sym *symbol
+	if named.Pkg != "" {
+		if pkgSym := sc.lookup(named.Pkg); pkgSym != nil && pkgSym.kind == symbolPackage {
+			sym = pkgSym.pkg.scope.lookupLocal(named.Name)
+			if sym != nil && sym.kind == symbolType {
+				if err := b.checkExported(t, sym, named.Pkg+"."+named.Name); err != nil {
+					return nil, err
+				}
+			}
+		}
+	} else {
+		sym = sc.lookup(named.Name)
+	}
+	if sym == nil || sym.kind != symbolType {
+		return nil, b.errorAt(t, "unknown type: %s", t)
+	}
+
+	typ := sym.typ
+	if typ.Generic == nil {
+		if len(named.Args) > 0 {
+			return nil, b.errorAt(t, "type %s is not generic", typ)
+		}
+		return typ, nil
+	}
+
+	if want := len(typ.Generic.decl.TypeParams); len(named.Args) != want {
+		return nil, b.errorAt(t, "generic type %s expects %d type arguments, got %d", typ, want, len(named.Args))
+	}
+	args := make([]*Type, 0, len(named.Args))
+	for _, arg := range named.Args {
+		argType, err := b.resolveType(arg, sc)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, argType)
+	}
+	return b.instantiateType(typ, args, t)
+}
+
+// llvmType returns the LLVM representation of a Guayavita type
+func (b *LLVMCodeBuilder) llvmType(t *Type) llvm.Type {
+	switch t.Kind {
+	case KindVoid:
+		return b.context.VoidType()
+	case KindBool:
+		return b.context.Int1Type()
+	case KindInt:
+		return b.context.IntType(t.Bits)
+	case KindFloat:
+		if t.Bits == 32 {
+			return b.context.FloatType()
+		}
+		return b.context.DoubleType()
+	case KindString:
+		return llvm.PointerType(b.context.Int8Type(), 0)
+	case KindStruct:
+		return b.structType(t)
+	case KindEnum:
+		return b.enumType(t)
+	case KindOptional:
+		return b.context.StructType([]llvm.Type{b.context.Int1Type(), b.llvmType(t.Elem)}, false)
+	case KindArray:
+		return llvm.ArrayType(b.llvmType(t.Elem), t.Len)
+	case KindSlice:
+		i64 := b.context.Int64Type()
+		return b.context.StructType([]llvm.Type{llvm.PointerType(b.llvmType(t.Elem), 0), i64, i64}, false)
+	case KindFunc:
+		i8Ptr := llvm.PointerType(b.context.Int8Type(), 0)
+		return b.context.StructType([]llvm.Type{llvm.PointerType(b.closureFnType(t), 0), i8Ptr}, false)
+	case KindInterface:
+		i8Ptr := llvm.PointerType(b.context.Int8Type(), 0)
+		return b.context.StructType([]llvm.Type{i8Ptr, llvm.PointerType(b.vtableType(t), 0)}, false)
+	case KindTuple:
+		elems := make([]llvm.Type, 0, len(t.Elems))
+		for _, elem := range t.Elems {
+			elems = append(elems, b.llvmType(elem))
+		}
+		return b.context.StructType(elems, false)
+	default:
+		panic("unhandled type kind: " + t.Name)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"jmpeax.com/guayavita/gvc/internal/syntax"
 	"tinygo.org/x/go-llvm"
 )
 
@@ -45,6 +46,12 @@ func (b *LLVMCodeBuilder) initializeLLVM() error {
 		b.module.SetTarget(b.config.Target)
 	}
 
+	// Sizes computed while generating IR (malloc'd slices, closure
+	// environments, enum payloads) must follow the ABI of the target
+	if err := b.setDataLayout(); err != nil {
+		return err
+	}
+
 	// Initialize external functions
 	b.initializeExternalFunctions()
 
@@ -56,6 +63,24 @@ func (b *LLVMCodeBuilder) initializeLLVM() error {
 	return nil
 }
 
+// setDataLayout sets the data layout of the module to the one of the target
+// machine, since the default layout aligns i64 to 4 bytes
+func (b *LLVMCodeBuilder) setDataLayout() error {
+	target, err := llvm.GetTargetFromTriple(b.getTargetTriple())
+	if err != nil {
+		return fmt.Errorf("failed to get target: %w", err)
+	}
+
+	machine := target.CreateTargetMachine(b.getTargetTriple(), "", "",
+		llvm.CodeGenLevelDefault, llvm.RelocDefault, llvm.CodeModelDefault)
+	defer machine.Dispose()
+
+	td := machine.CreateTargetData()
+	defer td.Dispose()
+	b.module.SetDataLayout(td.String())
+	return nil
+}
+
 // cleanup releases LLVM resources
 func (b *LLVMCodeBuilder) cleanup() {
 	if !b.builder.IsNil() {
@@ -68,3 +93,87 @@ func (b *LLVMCodeBuilder) cleanup() {
 		b.context.Dispose()
 	}
 }
+
+// createEntryAlloca allocates a stack slot in the entry block of the current
+// function, so that allocas inside loops do not grow the stack
+func (b *LLVMCodeBuilder) createEntryAlloca(t llvm.Type, name string) llvm.Value {
+	entry := b.fn.value.EntryBasicBlock()
+	builder := b.context.NewBuilder()
+	defer builder.Dispose()
+
+	if first := entry.FirstInstruction(); first.IsNil() {
+		builder.SetInsertPointAtEnd(entry)
+	} else {
+		builder.SetInsertPointBefore(first)
+	}
+	return builder.CreateAlloca(t, name)
+}
+
+// createLocal allocates the storage of a local variable of type t. Locals
+// that closures may capture are allocated on the heap each time they are
+// declared, so that closures share them with the function and with each
+// other, and can outlive it. The allocation is never freed.
+func (b *LLVMCodeBuilder) createLocal(t *Type, name string) llvm.Value {
+	if !b.fn.captured[name] {
+		return b.createEntryAlloca(b.llvmType(t), name)
+	}
+	raw := b.callExternal("malloc", b.elemSize(t))
+	return b.builder.CreateBitCast(raw, llvm.PointerType(b.llvmType(t), 0), name)
+}
+
+// isTerminated reports whether the current block already ends in a terminator
+func (b *LLVMCodeBuilder) isTerminated() bool {
+	last := b.builder.GetInsertBlock().LastInstruction()
+	if last.IsNil() {
+		return false
+	}
+	switch last.InstructionOpcode() {
+	case llvm.Ret, llvm.Br, llvm.Switch, llvm.IndirectBr, llvm.Unreachable:
+		return true
+	default:
+		return false
+	}
+}
+
+// isReachable reports whether the current block is the entry block or has
+// at least one predecessor
+func (b *LLVMCodeBuilder) isReachable() bool {
+	block := b.builder.GetInsertBlock()
+	if block == block.Parent().EntryBasicBlock() {
+		return true
+	}
+	return !block.AsValue().FirstUse().IsNil()
+}
+
+// callExternal calls a libc function registered in the external registry;
+// the names used by the generator are always registered
+func (b *LLVMCodeBuilder) callExternal(name string, args ...llvm.Value) llvm.Value {
+	fn, err := b.declareExternalFunction(name)
+	if err != nil {
+		panic(err)
+	}
+	callName := name
+	if fn.GlobalValueType().ReturnType().TypeKind() == llvm.VoidTypeKind {
+		callName = ""
+	}
+	return b.builder.CreateCall(fn.GlobalValueType(), fn, args, callName)
+}
+
+// generateCheck aborts the program when ok is false, reporting msg and the
+// source position of node on stderr
+func (b *LLVMCodeBuilder) generateCheck(ok llvm.Value, node syntax.Node, msg string) {
+	failBlock := b.context.AddBasicBlock(b.fn.value, "check.fail")
+	okBlock := b.context.AddBasicBlock(b.fn.value, "check.ok")
+	b.builder.CreateCondBr(ok, okBlock, failBlock)
+
+	b.builder.SetInsertPointAtEnd(failBlock)
+	pos := node.Pos()
+	report := fmt.Sprintf("%s:%d:%d: runtime error: %s\n", pos.File, pos.Line, pos.Column, msg)
+	format := b.builder.CreateGlobalStringPtr("%s", "fmt")
+	stderr := llvm.ConstInt(b.context.Int32Type(), 2, false)
+	b.callExternal("dprintf", stderr, format, b.builder.CreateGlobalStringPtr(report, "panic"))
+	b.callExternal("abort")
+	b.builder.CreateUnreachable()
+
+	b.builder.SetInsertPointAtEnd(okBlock)
+}
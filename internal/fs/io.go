@@ -2,8 +2,13 @@ package fs
 
 import (
 	"os"
+	"path/filepath"
+	"sort"
 )
 
+// SourceExt is the file extension of Guayavita source files
+const SourceExt = ".gvt"
+
 func ReadFile(filename string) (string, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
@@ -12,3 +17,22 @@ func ReadFile(filename string) (string, error) {
 	content := string(data)
 	return content, nil
 }
+
+// ListSourceFiles returns the Guayavita source files directly inside dir, sorted by name
+func ListSourceFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != SourceExt {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
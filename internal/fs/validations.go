@@ -25,3 +25,23 @@ func ValidateFile(path string) error {
 
 	return nil
 }
+
+// ValidateDir checks if the given path points to a readable directory
+func ValidateDir(path string) error {
+	resolvedPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("invalid path or broken symlink: %w", err)
+	}
+	fileInfo, err := os.Stat(resolvedPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("directory does not exist: %s", path)
+		}
+		return fmt.Errorf("cannot access directory: %w", err)
+	}
+	if !fileInfo.IsDir() {
+		return fmt.Errorf("path is not a directory: %s", path)
+	}
+
+	return nil
+}
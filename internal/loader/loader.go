@@ -0,0 +1,181 @@
+package loader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jmpeax.com/guayavita/gvc/internal/diag"
+	"jmpeax.com/guayavita/gvc/internal/fs"
+	"jmpeax.com/guayavita/gvc/internal/syntax"
+)
+
+// Program is the set of packages reachable from the entry package
+type Program struct {
+	Packages []*syntax.Package // dependencies first, entry package last
+	Sources  map[string]string // file name -> source, for diagnostics rendering
+}
+
+// Entry returns the package compilation started from
+func (p *Program) Entry() *syntax.Package {
+	if len(p.Packages) == 0 {
+		return nil
+	}
+	return p.Packages[len(p.Packages)-1]
+}
+
+type loader struct {
+	resolver    *Resolver
+	program     *Program
+	loaded      map[string]*syntax.Package
+	stack       []string // packages currently being loaded, for cycle detection
+	diagnostics []diag.Diagnostic
+}
+
+// Load parses the package at input, which is either a single .gvt file or a
+// directory of .gvt files, together with every package it transitively imports.
+// Imports are resolved relative to root; when root is empty the directory of
+// input is used. Parse and import errors are returned as diagnostics, I/O
+// errors on the entry package as an error.
+func Load(input, root string) (*Program, []diag.Diagnostic, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot access %s: %w", input, err)
+	}
+
+	var files []string
+	dir := input
+	if info.IsDir() {
+		if files, err = fs.ListSourceFiles(input); err != nil {
+			return nil, nil, err
+		}
+		if len(files) == 0 {
+			return nil, nil, fmt.Errorf("no %s files found in %s", fs.SourceExt, input)
+		}
+	} else {
+		if err := fs.ValidateFile(input); err != nil {
+			return nil, nil, err
+		}
+		files = []string{input}
+		dir = filepath.Dir(input)
+	}
+
+	if root == "" {
+		root = dir
+	}
+
+	l := &loader{
+		resolver: NewResolver(root),
+		program:  &Program{Sources: map[string]string{}},
+		loaded:   map[string]*syntax.Package{},
+	}
+
+	pkg, err := l.parsePackage("", dir, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pkg != nil {
+		l.loadImports(pkg)
+		l.program.Packages = append(l.program.Packages, pkg)
+	}
+
+	return l.program, l.diagnostics, nil
+}
+
+// parsePackage parses the given files into a package. When path is not empty
+// every file must declare exactly that package path.
+func (l *loader) parsePackage(path, dir string, files []string) (*syntax.Package, error) {
+	pkg := &syntax.Package{Path: path, Dir: dir}
+
+	for _, name := range files {
+		content, err := fs.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+		l.program.Sources[name] = content
+
+		file, diagnostics := syntax.ParseFile(name, content)
+		l.diagnostics = append(l.diagnostics, diagnostics...)
+		if len(diagnostics) > 0 {
+			continue
+		}
+
+		if pkg.Path == "" {
+			pkg.Path = file.Package
+		}
+		if file.Package != pkg.Path {
+			l.errorAt(file.Pos(), fmt.Sprintf("file declares package %s, expected %s", file.Package, pkg.Path))
+			continue
+		}
+		pkg.Files = append(pkg.Files, file)
+	}
+
+	if len(pkg.Files) == 0 {
+		return nil, nil
+	}
+	return pkg, nil
+}
+
+// loadImports loads every package imported by pkg before pkg itself is
+// appended to the program, keeping dependencies first
+func (l *loader) loadImports(pkg *syntax.Package) {
+	l.stack = append(l.stack, pkg.Path)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	for _, file := range pkg.Files {
+		for _, imp := range file.Imports {
+			l.loadImport(imp)
+		}
+	}
+}
+
+func (l *loader) loadImport(imp *syntax.ImportDecl) {
+	for i, path := range l.stack {
+		if path == imp.Path {
+			cycle := append(append([]string{}, l.stack[i:]...), imp.Path)
+			l.errorAt(imp.Pos(), "import cycle not allowed: "+strings.Join(cycle, " -> "))
+			return
+		}
+	}
+
+	if _, ok := l.loaded[imp.Path]; ok {
+		return
+	}
+
+	dir, err := l.resolver.Resolve(imp.Path)
+	if err != nil {
+		l.errorAt(imp.Pos(), err.Error())
+		return
+	}
+
+	files, err := fs.ListSourceFiles(dir)
+	if err != nil {
+		l.errorAt(imp.Pos(), err.Error())
+		return
+	}
+
+	pkg, err := l.parsePackage(imp.Path, dir, files)
+	if err != nil {
+		l.errorAt(imp.Pos(), err.Error())
+		return
+	}
+	if pkg == nil {
+		return
+	}
+
+	l.loaded[imp.Path] = pkg
+	l.loadImports(pkg)
+	l.program.Packages = append(l.program.Packages, pkg)
+}
+
+func (l *loader) errorAt(pos diag.Position, msg string) {
+	l.diagnostics = append(l.diagnostics, diag.Diagnostic{
+		Severity: diag.Error,
+		Message:  msg,
+		Span: diag.Span{
+			Start: pos,
+			End:   pos,
+		},
+	})
+}
@@ -0,0 +1,49 @@
+package loader
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func repoPath(rel string) string {
+	return filepath.Join("..", "..", rel)
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	r := NewResolver(repoPath(filepath.Join("test-data", "packages")))
+	dir, err := r.Resolve("util.math")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Base(dir) != "math" || filepath.Base(filepath.Dir(dir)) != "util" {
+		t.Fatalf("expected util/math directory, got %s", dir)
+	}
+	if _, err := r.Resolve("util.missing"); err == nil {
+		t.Fatalf("expected error for missing package")
+	}
+}
+
+func TestLoad_PackageDirectory(t *testing.T) {
+	program, diags, err := Load(repoPath(filepath.Join("test-data", "packages")), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	// Dependencies come first, the entry package last
+	var paths []string
+	for _, pkg := range program.Packages {
+		paths = append(paths, pkg.Path)
+	}
+	if len(paths) != 3 || program.Entry().Path != "main" {
+		t.Fatalf("expected util.math, util.greet and main, got %v", paths)
+	}
+
+	for _, pkg := range program.Packages {
+		if pkg.Path == "util.math" && len(pkg.Files) != 2 {
+			t.Fatalf("expected util.math to merge 2 files, got %d", len(pkg.Files))
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package loader
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"jmpeax.com/guayavita/gvc/internal/fs"
+)
+
+// Resolver maps dotted package paths onto directories below a root directory,
+// so that package util.strings lives in <root>/util/strings
+type Resolver struct {
+	Root string
+}
+
+// NewResolver creates a resolver rooted at the given directory
+func NewResolver(root string) *Resolver {
+	return &Resolver{Root: root}
+}
+
+// Dir returns the directory a package path maps to, without checking that it exists
+func (r *Resolver) Dir(path string) string {
+	return filepath.Join(r.Root, filepath.Join(strings.Split(path, ".")...))
+}
+
+// Resolve returns the directory holding the sources of the given package path
+func (r *Resolver) Resolve(path string) (string, error) {
+	dir := r.Dir(path)
+	if err := fs.ValidateDir(dir); err != nil {
+		return "", fmt.Errorf("cannot find package %s in %s: %w", path, r.Root, err)
+	}
+
+	files, err := fs.ListSourceFiles(dir)
+	if err != nil {
+		return "", fmt.Errorf("cannot read package %s: %w", path, err)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("package %s has no %s files in %s", path, fs.SourceExt, dir)
+	}
+
+	return dir, nil
+}
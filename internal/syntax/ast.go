@@ -1,6 +1,11 @@
 package syntax
 
-import "jmpeax.com/guayavita/gvc/internal/diag"
+import (
+	"strconv"
+	"strings"
+
+	"jmpeax.com/guayavita/gvc/internal/diag"
+)
 
 // Core AST interfaces
 type Node interface {
@@ -24,39 +29,153 @@ type Decl interface {
 
 // File represents a complete source file
 type File struct {
-	Package string
+	Package string // dotted package path, e.g. "util.strings"
+	Imports []*ImportDecl
 	Decls   []Decl
 	Pos_    diag.Position
 }
 
 func (f *File) Pos() diag.Position { return f.Pos_ }
 
+// Package groups every file that declares the same package path
+type Package struct {
+	Path  string
+	Dir   string
+	Files []*File
+}
+
+// ImportDecl represents a single import clause. A grouped import
+// (import { a, b.c }) produces one ImportDecl per clause.
+type ImportDecl struct {
+	Path    string   // dotted package path
+	Symbols []string // optional symbol list, empty when importing the whole package
+	Alias   string   // optional alias, empty if not specified
+	Pos_    diag.Position
+}
+
+func (d *ImportDecl) Pos() diag.Position { return d.Pos_ }
+func (d *ImportDecl) declNode()          {}
+
+// Name returns the identifier the package is bound to in the importing file
+func (d *ImportDecl) Name() string {
+	if d.Alias != "" {
+		return d.Alias
+	}
+	return d.Path[strings.LastIndex(d.Path, ".")+1:]
+}
+
 // Declarations
 type FunDecl struct {
-	Name   string
-	Params []Param
-	Type   string
-	Body   *Block
-	Pos_   diag.Position
+	Name       string
+	TypeParams []string   // type parameters of a generic function
+	Bounds     []TypeExpr // interface bound of each type parameter, nil when unbounded
+	Params     []Param
+	Type       TypeExpr
+	Body       *Block
+	Exported   bool // declared with export, visible to other packages
+	Pos_       diag.Position
 }
 
 func (d *FunDecl) Pos() diag.Position { return d.Pos_ }
 func (d *FunDecl) declNode()          {}
 
+// HasReceiver reports whether the function is a method taking self
+func (d *FunDecl) HasReceiver() bool {
+	return len(d.Params) > 0 && d.Params[0].Name == ReceiverName && d.Params[0].Type == nil
+}
+
 type VarDecl struct {
-	Name string
-	Type string // optional, empty if not specified
-	Init Expr
-	Pos_ diag.Position
+	Name     string
+	Names    []string // elements of a destructured tuple, def (q, r) = e; Name is empty
+	Type     TypeExpr // optional, nil if not specified
+	Init     Expr
+	Exported bool // declared with export, visible to other packages
+	Pos_     diag.Position
 }
 
 func (d *VarDecl) Pos() diag.Position { return d.Pos_ }
 func (d *VarDecl) declNode()          {}
 func (d *VarDecl) stmtNode()          {}
 
+// TypeDecl represents a named type declaration: type Name = struct { ... }
+// or type Name = enum { ... }. Exactly one of Struct and Enum is set.
+type TypeDecl struct {
+	Name       string
+	TypeParams []string   // type parameters of a generic type
+	Bounds     []TypeExpr // interface bound of each type parameter, nil when unbounded
+	Struct     *StructType
+	Enum       *EnumType
+	Interface  *InterfaceType
+	Exported   bool // declared with export, visible to other packages
+	Pos_       diag.Position
+}
+
+func (d *TypeDecl) Pos() diag.Position { return d.Pos_ }
+func (d *TypeDecl) declNode()          {}
+
+// StructType represents the body of a struct declaration
+type StructType struct {
+	Fields []Field
+	Pos_   diag.Position
+}
+
+func (t *StructType) Pos() diag.Position { return t.Pos_ }
+
+type Field struct {
+	Name string
+	Type TypeExpr
+	Pos_ diag.Position
+}
+
+func (f *Field) Pos() diag.Position { return f.Pos_ }
+
+// EnumType represents the body of an enum declaration
+type EnumType struct {
+	Variants []Variant
+	Pos_     diag.Position
+}
+
+func (t *EnumType) Pos() diag.Position { return t.Pos_ }
+
+// Variant is an enum variant with an optional payload: Circle(f64)
+type Variant struct {
+	Name  string
+	Types []TypeExpr // payload types, empty for plain variants
+	Pos_  diag.Position
+}
+
+func (v *Variant) Pos() diag.Position { return v.Pos_ }
+
+// InterfaceType represents the body of an interface declaration. Its methods
+// are signatures without body, each taking self.
+type InterfaceType struct {
+	Methods []*FunDecl
+	Pos_    diag.Position
+}
+
+func (t *InterfaceType) Pos() diag.Position { return t.Pos_ }
+
+// ImplDecl attaches methods to a type declared in the same package:
+// impl Point { fun len(self) : i32 { ... } }. With an interface the methods
+// implement it for the type: impl Shape for Circle { ... }
+type ImplDecl struct {
+	TypeParams []string // type parameters of a generic impl block
+	Interface  TypeExpr // implemented interface, nil for inherent methods
+	Type       string
+	Methods    []*FunDecl
+	Pos_       diag.Position
+}
+
+func (d *ImplDecl) Pos() diag.Position { return d.Pos_ }
+func (d *ImplDecl) declNode()          {}
+
+// ReceiverName is the name of the parameter that receives the value a method
+// is called on. It must be the first parameter and carries no type.
+const ReceiverName = "self"
+
 type Param struct {
 	Name string
-	Type string
+	Type TypeExpr // nil for the self receiver
 	Pos_ diag.Position
 }
 
@@ -71,8 +190,11 @@ type Block struct {
 func (s *Block) Pos() diag.Position { return s.Pos_ }
 func (s *Block) stmtNode()          {}
 
+// AssignStmt stores into a variable, field or element: x = e, or with a
+// compound operator such as x += e
 type AssignStmt struct {
 	Left  Expr
+	Op    string // "=" or a compound operator such as "+=" or "<<="
 	Right Expr
 	Pos_  diag.Position
 }
@@ -107,25 +229,230 @@ func (s *IfStmt) Pos() diag.Position { return s.Pos_ }
 func (s *IfStmt) stmtNode()          {}
 
 type WhileStmt struct {
-	Cond Expr
-	Body *Block
-	Pos_ diag.Position
+	Label string // optional, target of labeled break and continue
+	Cond  Expr
+	Body  *Block
+	Pos_  diag.Position
 }
 
 func (s *WhileStmt) Pos() diag.Position { return s.Pos_ }
 func (s *WhileStmt) stmtNode()          {}
 
+// ForStmt is a C-style loop: for (def i = 0; i < n; i += 1) { ... }. Every
+// clause is optional; without a condition the loop only ends by leaving the
+// body.
+type ForStmt struct {
+	Label string // optional, target of labeled break and continue
+	Init  Stmt   // optional
+	Cond  Expr   // optional
+	Post  Stmt   // optional
+	Body  *Block
+	Pos_  diag.Position
+}
+
+func (s *ForStmt) Pos() diag.Position { return s.Pos_ }
+func (s *ForStmt) stmtNode()          {}
+
 type ForInStmt struct {
-	Var  string
-	Iter Expr
-	Body *Block
-	Pos_ diag.Position
+	Label string // optional, target of labeled break and continue
+	Var   string
+	Iter  Expr
+	Body  *Block
+	Pos_  diag.Position
 }
 
 func (s *ForInStmt) Pos() diag.Position { return s.Pos_ }
 func (s *ForInStmt) stmtNode()          {}
 
+// BranchStmt leaves the innermost loop, or the loop with the given label:
+// break, continue outer
+type BranchStmt struct {
+	Tok   TokenKind // BREAK or CONTINUE
+	Label string    // optional
+	Pos_  diag.Position
+}
+
+func (s *BranchStmt) Pos() diag.Position { return s.Pos_ }
+func (s *BranchStmt) stmtNode()          {}
+
+// MatchStmt branches on the variant of an enum value:
+// match shape { Circle(r) -> { ... } else -> { ... } }
+type MatchStmt struct {
+	X    Expr
+	Arms []MatchArm
+	Else *Block // optional, covers every variant without an arm
+	Pos_ diag.Position
+}
+
+func (s *MatchStmt) Pos() diag.Position { return s.Pos_ }
+func (s *MatchStmt) stmtNode()          {}
+
+// MatchArm handles one variant, binding its payload to Bindings
+type MatchArm struct {
+	Variant  string
+	Bindings []string
+	Body     *Block
+	Pos_     diag.Position
+}
+
+func (a *MatchArm) Pos() diag.Position { return a.Pos_ }
+
+// HandleStmt branches on a Result:
+// handle expr { Ok(v) -> { ... } Err(e) -> { ... } }
+type HandleStmt struct {
+	X    Expr
+	Ok   *HandleBranch
+	Err  *HandleBranch
+	Pos_ diag.Position
+}
+
+func (s *HandleStmt) Pos() diag.Position { return s.Pos_ }
+func (s *HandleStmt) stmtNode()          {}
+
+// HandleBranch binds the payload of one Result variant to Name
+type HandleBranch struct {
+	Name string
+	Body *Block
+	Pos_ diag.Position
+}
+
+func (b *HandleBranch) Pos() diag.Position { return b.Pos_ }
+
+// Types
+
+// TypeExpr is a type written in the source
+type TypeExpr interface {
+	Node
+	String() string
+	typeNode()
+}
+
+// NamedType references a primitive or declared type, optionally qualified
+// by a package and instantiated with type arguments: i32, geo.Point, Box<T>
+type NamedType struct {
+	Pkg  string // package name or alias, empty if unqualified
+	Name string
+	Args []TypeExpr
+	Pos_ diag.Position
+}
+
+func (t *NamedType) Pos() diag.Position { return t.Pos_ }
+func (t *NamedType) typeNode()          {}
+
+func (t *NamedType) String() string {
+	name := t.Name
+	if t.Pkg != "" {
+		name = t.Pkg + "." + name
+	}
+	if len(t.Args) > 0 {
+		args := make([]string, 0, len(t.Args))
+		for _, arg := range t.Args {
+			args = append(args, arg.String())
+		}
+		name += "<" + strings.Join(args, ", ") + ">"
+	}
+	return name
+}
+
+// OptionalType is a type whose values may be none: T?
+type OptionalType struct {
+	Elem TypeExpr
+	Pos_ diag.Position
+}
+
+func (t *OptionalType) Pos() diag.Position { return t.Pos_ }
+func (t *OptionalType) typeNode()          {}
+func (t *OptionalType) String() string     { return t.Elem.String() + "?" }
+
+// ArrayType is a fixed-size array of a primitive type: i32[4]
+type ArrayType struct {
+	Elem TypeExpr
+	Len  int
+	Pos_ diag.Position
+}
+
+func (t *ArrayType) Pos() diag.Position { return t.Pos_ }
+func (t *ArrayType) typeNode()          {}
+func (t *ArrayType) String() string     { return t.Elem.String() + "[" + strconv.Itoa(t.Len) + "]" }
+
+// SliceType is a growable sequence of elements: [T*]
+type SliceType struct {
+	Elem TypeExpr
+	Pos_ diag.Position
+}
+
+func (t *SliceType) Pos() diag.Position { return t.Pos_ }
+func (t *SliceType) typeNode()          {}
+func (t *SliceType) String() string     { return "[" + t.Elem.String() + "*]" }
+
+// FuncType is the type of a function value: fun(i32, i32) : i32
+type FuncType struct {
+	Params []TypeExpr
+	Result TypeExpr
+	Pos_   diag.Position
+}
+
+func (t *FuncType) Pos() diag.Position { return t.Pos_ }
+func (t *FuncType) typeNode()          {}
+
+func (t *FuncType) String() string {
+	params := make([]string, 0, len(t.Params))
+	for _, param := range t.Params {
+		params = append(params, param.String())
+	}
+	return "fun(" + strings.Join(params, ", ") + ") : " + t.Result.String()
+}
+
+// TupleType is an anonymous product of two or more types: (i32, string)
+type TupleType struct {
+	Elems []TypeExpr
+	Pos_  diag.Position
+}
+
+func (t *TupleType) Pos() diag.Position { return t.Pos_ }
+func (t *TupleType) typeNode()          {}
+
+func (t *TupleType) String() string {
+	elems := make([]string, 0, len(t.Elems))
+	for _, elem := range t.Elems {
+		elems = append(elems, elem.String())
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
+}
+
 // Expressions
+
+// TupleExpr builds a tuple from two or more values: (q, r)
+type TupleExpr struct {
+	Elems []Expr
+	Pos_  diag.Position
+}
+
+func (e *TupleExpr) Pos() diag.Position { return e.Pos_ }
+func (e *TupleExpr) exprNode()          {}
+
+// RangeExpr is a half-open a..b or inclusive a..=b range of integers. As a
+// slice bound either end may be omitted: s[..n], s[i..].
+type RangeExpr struct {
+	Low       Expr // nil if omitted
+	High      Expr // nil if omitted
+	Inclusive bool
+	Pos_      diag.Position
+}
+
+func (e *RangeExpr) Pos() diag.Position { return e.Pos_ }
+func (e *RangeExpr) exprNode()          {}
+
+// IndexExpr selects an element or, with a range index, a slice: x[i], x[a..b]
+type IndexExpr struct {
+	X     Expr
+	Index Expr
+	Pos_  diag.Position
+}
+
+func (e *IndexExpr) Pos() diag.Position { return e.Pos_ }
+func (e *IndexExpr) exprNode()          {}
+
 type BinaryExpr struct {
 	Left  Expr
 	Op    string
@@ -145,6 +472,16 @@ type UnaryExpr struct {
 func (e *UnaryExpr) Pos() diag.Position { return e.Pos_ }
 func (e *UnaryExpr) exprNode()          {}
 
+// CastExpr converts a numeric value to another numeric type: x as T
+type CastExpr struct {
+	X    Expr
+	Type TypeExpr
+	Pos_ diag.Position
+}
+
+func (e *CastExpr) Pos() diag.Position { return e.Pos_ }
+func (e *CastExpr) exprNode()          {}
+
 type CallExpr struct {
 	Fun  Expr
 	Args []Expr
@@ -154,6 +491,27 @@ type CallExpr struct {
 func (e *CallExpr) Pos() diag.Position { return e.Pos_ }
 func (e *CallExpr) exprNode()          {}
 
+// TryExpr unwraps the Ok value of a Result and returns the Err from the
+// enclosing function otherwise: expr?
+type TryExpr struct {
+	X    Expr
+	Pos_ diag.Position // position of the '?'
+}
+
+func (e *TryExpr) Pos() diag.Position { return e.Pos_ }
+func (e *TryExpr) exprNode()          {}
+
+// SelectorExpr represents a qualified reference such as pkg.name or a
+// field access such as point.x
+type SelectorExpr struct {
+	X    Expr
+	Sel  string
+	Pos_ diag.Position
+}
+
+func (e *SelectorExpr) Pos() diag.Position { return e.Pos_ }
+func (e *SelectorExpr) exprNode()          {}
+
 type Ident struct {
 	Name string
 	Pos_ diag.Position
@@ -163,7 +521,7 @@ func (e *Ident) Pos() diag.Position { return e.Pos_ }
 func (e *Ident) exprNode()          {}
 
 type BasicLit struct {
-	Kind  string // "INT", "FLOAT", "STRING", "BOOL", "NONE"
+	Kind  string // "INT", "FLOAT", "STRING", "CHAR", "BOOL", "NONE"
 	Value string
 	Pos_  diag.Position
 }
@@ -171,6 +529,17 @@ type BasicLit struct {
 func (e *BasicLit) Pos() diag.Position { return e.Pos_ }
 func (e *BasicLit) exprNode()          {}
 
+// InterpolatedString is a string literal with embedded expressions,
+// "sum is ${a + b}". Parts holds the text as STRING literals, omitting empty
+// ones, and the expressions in source order.
+type InterpolatedString struct {
+	Parts []Expr
+	Pos_  diag.Position
+}
+
+func (e *InterpolatedString) Pos() diag.Position { return e.Pos_ }
+func (e *InterpolatedString) exprNode()          {}
+
 type ArrayLit struct {
 	Elements []Expr
 	Pos_     diag.Position
@@ -178,3 +547,33 @@ type ArrayLit struct {
 
 func (e *ArrayLit) Pos() diag.Position { return e.Pos_ }
 func (e *ArrayLit) exprNode()          {}
+
+// FuncLit is an anonymous function, which captures the local variables it
+// uses: fun(x: i32) : i32 { return x + n }
+type FuncLit struct {
+	Params []Param
+	Type   TypeExpr
+	Body   *Block
+	Pos_   diag.Position
+}
+
+func (e *FuncLit) Pos() diag.Position { return e.Pos_ }
+func (e *FuncLit) exprNode()          {}
+
+// StructLit represents a struct literal: Point { x: 1, y: 2 }
+type StructLit struct {
+	Type   Expr // *Ident or *SelectorExpr naming the struct type
+	Fields []FieldInit
+	Pos_   diag.Position
+}
+
+func (e *StructLit) Pos() diag.Position { return e.Pos_ }
+func (e *StructLit) exprNode()          {}
+
+type FieldInit struct {
+	Name  string
+	Value Expr
+	Pos_  diag.Position
+}
+
+func (f *FieldInit) Pos() diag.Position { return f.Pos_ }
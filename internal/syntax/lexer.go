@@ -3,6 +3,7 @@ package syntax
 import (
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"jmpeax.com/guayavita/gvc/internal/diag"
 )
@@ -19,30 +20,42 @@ const (
 	INT    TokenKind = "INT"
 	FLOAT  TokenKind = "FLOAT"
 	STRING TokenKind = "STRING"
+	CHAR   TokenKind = "CHAR"
 	TRUE   TokenKind = "TRUE"
 	FALSE  TokenKind = "FALSE"
 	NONE   TokenKind = "NONE"
 
+	// Parts of an interpolated string "a${x}b${y}c": STRING_START is "a",
+	// STRING_MID is "b" and STRING_END is "c", with the tokens of x and y
+	// in between
+	STRING_START TokenKind = "STRING_START"
+	STRING_MID   TokenKind = "STRING_MID"
+	STRING_END   TokenKind = "STRING_END"
+
 	// Keywords
-	PACKAGE TokenKind = "PACKAGE"
-	IMPORT  TokenKind = "IMPORT"
-	DEF     TokenKind = "DEF"
-	FUN     TokenKind = "FUN"
-	TYPE    TokenKind = "TYPE"
-	EXPORT  TokenKind = "EXPORT"
-	RETURN  TokenKind = "RETURN"
-	IF      TokenKind = "IF"
-	ELSE    TokenKind = "ELSE"
-	WHILE   TokenKind = "WHILE"
-	FOR     TokenKind = "FOR"
-	IN      TokenKind = "IN"
-	HANDLE  TokenKind = "HANDLE"
-	OK      TokenKind = "OK"
-	ERR     TokenKind = "ERR"
-	STRUCT  TokenKind = "STRUCT"
-	ENUM    TokenKind = "ENUM"
-	IMPL    TokenKind = "IMPL"
-	AS      TokenKind = "AS"
+	PACKAGE   TokenKind = "PACKAGE"
+	IMPORT    TokenKind = "IMPORT"
+	DEF       TokenKind = "DEF"
+	FUN       TokenKind = "FUN"
+	TYPE      TokenKind = "TYPE"
+	EXPORT    TokenKind = "EXPORT"
+	RETURN    TokenKind = "RETURN"
+	IF        TokenKind = "IF"
+	ELSE      TokenKind = "ELSE"
+	WHILE     TokenKind = "WHILE"
+	FOR       TokenKind = "FOR"
+	IN        TokenKind = "IN"
+	HANDLE    TokenKind = "HANDLE"
+	OK        TokenKind = "OK"
+	ERR       TokenKind = "ERR"
+	STRUCT    TokenKind = "STRUCT"
+	ENUM      TokenKind = "ENUM"
+	INTERFACE TokenKind = "INTERFACE"
+	IMPL      TokenKind = "IMPL"
+	AS        TokenKind = "AS"
+	MATCH     TokenKind = "MATCH"
+	BREAK     TokenKind = "BREAK"
+	CONTINUE  TokenKind = "CONTINUE"
 
 	// Operators
 	ASSIGN TokenKind = "="
@@ -61,11 +74,33 @@ const (
 	OR     TokenKind = "||"
 	NOT    TokenKind = "!"
 
+	// Bitwise operators
+	BIT_AND TokenKind = "&"
+	BIT_OR  TokenKind = "|"
+	BIT_XOR TokenKind = "^"
+	BIT_NOT TokenKind = "~"
+	SHL     TokenKind = "<<"
+	SHR     TokenKind = ">>"
+
+	// Assignment operators
+	PLUS_ASSIGN  TokenKind = "+="
+	MINUS_ASSIGN TokenKind = "-="
+	MUL_ASSIGN   TokenKind = "*="
+	DIV_ASSIGN   TokenKind = "/="
+	MOD_ASSIGN   TokenKind = "%="
+	AND_ASSIGN   TokenKind = "&="
+	OR_ASSIGN    TokenKind = "|="
+	XOR_ASSIGN   TokenKind = "^="
+	SHL_ASSIGN   TokenKind = "<<="
+	SHR_ASSIGN   TokenKind = ">>="
+
 	// Punctuation
 	COMMA     TokenKind = ","
 	SEMICOLON TokenKind = ";"
 	COLON     TokenKind = ":"
 	DOT       TokenKind = "."
+	DOTDOT    TokenKind = ".."
+	DOTDOTEQ  TokenKind = "..="
 	ARROW     TokenKind = "->"
 	QUESTION  TokenKind = "?"
 
@@ -85,28 +120,32 @@ type Token struct {
 }
 
 var keywords = map[string]TokenKind{
-	"package": PACKAGE,
-	"import":  IMPORT,
-	"def":     DEF,
-	"fun":     FUN,
-	"type":    TYPE,
-	"export":  EXPORT,
-	"return":  RETURN,
-	"if":      IF,
-	"else":    ELSE,
-	"while":   WHILE,
-	"for":     FOR,
-	"in":      IN,
-	"handle":  HANDLE,
-	"Ok":      OK,
-	"Err":     ERR,
-	"struct":  STRUCT,
-	"enum":    ENUM,
-	"impl":    IMPL,
-	"as":      AS,
-	"true":    TRUE,
-	"false":   FALSE,
-	"none":    NONE,
+	"package":   PACKAGE,
+	"import":    IMPORT,
+	"def":       DEF,
+	"fun":       FUN,
+	"type":      TYPE,
+	"export":    EXPORT,
+	"return":    RETURN,
+	"if":        IF,
+	"else":      ELSE,
+	"while":     WHILE,
+	"for":       FOR,
+	"in":        IN,
+	"handle":    HANDLE,
+	"Ok":        OK,
+	"Err":       ERR,
+	"struct":    STRUCT,
+	"enum":      ENUM,
+	"interface": INTERFACE,
+	"impl":      IMPL,
+	"as":        AS,
+	"match":     MATCH,
+	"break":     BREAK,
+	"continue":  CONTINUE,
+	"true":      TRUE,
+	"false":     FALSE,
+	"none":      NONE,
 }
 
 type Lexer struct {
@@ -117,6 +156,7 @@ type Lexer struct {
 	ch       byte // current char under examination
 	line     int
 	column   int
+	interps  []int // brace depth in each open ${ of interpolated strings
 }
 
 func NewLexer(input, filename string) *Lexer {
@@ -188,6 +228,9 @@ func (l *Lexer) NextToken() Token {
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = Token{Kind: LE, Value: "<=", Pos: tok.Pos}
+		} else if l.peekChar() == '<' {
+			l.readChar()
+			tok = l.readOperator(SHL, SHL_ASSIGN, tok.Pos)
 		} else {
 			tok = Token{Kind: LT, Value: string(l.ch), Pos: tok.Pos}
 		}
@@ -195,6 +238,9 @@ func (l *Lexer) NextToken() Token {
 		if l.peekChar() == '=' {
 			l.readChar()
 			tok = Token{Kind: GE, Value: ">=", Pos: tok.Pos}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = l.readOperator(SHR, SHR_ASSIGN, tok.Pos)
 		} else {
 			tok = Token{Kind: GT, Value: string(l.ch), Pos: tok.Pos}
 		}
@@ -203,30 +249,37 @@ func (l *Lexer) NextToken() Token {
 			l.readChar()
 			tok = Token{Kind: AND, Value: "&&", Pos: tok.Pos}
 		} else {
-			tok = Token{Kind: ILLEGAL, Value: string(l.ch), Pos: tok.Pos}
+			tok = l.readOperator(BIT_AND, AND_ASSIGN, tok.Pos)
 		}
 	case '|':
 		if l.peekChar() == '|' {
 			l.readChar()
 			tok = Token{Kind: OR, Value: "||", Pos: tok.Pos}
 		} else {
-			tok = Token{Kind: ILLEGAL, Value: string(l.ch), Pos: tok.Pos}
+			tok = l.readOperator(BIT_OR, OR_ASSIGN, tok.Pos)
 		}
+	case '^':
+		tok = l.readOperator(BIT_XOR, XOR_ASSIGN, tok.Pos)
+	case '~':
+		tok = Token{Kind: BIT_NOT, Value: string(l.ch), Pos: tok.Pos}
 	case '-':
 		if l.peekChar() == '>' {
 			l.readChar()
 			tok = Token{Kind: ARROW, Value: "->", Pos: tok.Pos}
+		} else if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Kind: MINUS_ASSIGN, Value: "-=", Pos: tok.Pos}
 		} else {
 			tok = Token{Kind: MINUS, Value: string(l.ch), Pos: tok.Pos}
 		}
 	case '+':
-		tok = Token{Kind: PLUS, Value: string(l.ch), Pos: tok.Pos}
+		tok = l.readOperator(PLUS, PLUS_ASSIGN, tok.Pos)
 	case '*':
-		tok = Token{Kind: MUL, Value: string(l.ch), Pos: tok.Pos}
+		tok = l.readOperator(MUL, MUL_ASSIGN, tok.Pos)
 	case '/':
-		tok = Token{Kind: DIV, Value: string(l.ch), Pos: tok.Pos}
+		tok = l.readOperator(DIV, DIV_ASSIGN, tok.Pos)
 	case '%':
-		tok = Token{Kind: MOD, Value: string(l.ch), Pos: tok.Pos}
+		tok = l.readOperator(MOD, MOD_ASSIGN, tok.Pos)
 	case ',':
 		tok = Token{Kind: COMMA, Value: string(l.ch), Pos: tok.Pos}
 	case ';':
@@ -234,7 +287,17 @@ func (l *Lexer) NextToken() Token {
 	case ':':
 		tok = Token{Kind: COLON, Value: string(l.ch), Pos: tok.Pos}
 	case '.':
-		tok = Token{Kind: DOT, Value: string(l.ch), Pos: tok.Pos}
+		if l.peekChar() == '.' {
+			l.readChar()
+			if l.peekChar() == '=' {
+				l.readChar()
+				tok = Token{Kind: DOTDOTEQ, Value: "..=", Pos: tok.Pos}
+			} else {
+				tok = Token{Kind: DOTDOT, Value: "..", Pos: tok.Pos}
+			}
+		} else {
+			tok = Token{Kind: DOT, Value: string(l.ch), Pos: tok.Pos}
+		}
 	case '?':
 		tok = Token{Kind: QUESTION, Value: string(l.ch), Pos: tok.Pos}
 	case '(':
@@ -242,29 +305,56 @@ func (l *Lexer) NextToken() Token {
 	case ')':
 		tok = Token{Kind: RPAREN, Value: string(l.ch), Pos: tok.Pos}
 	case '{':
+		if n := len(l.interps); n > 0 {
+			l.interps[n-1]++
+		}
 		tok = Token{Kind: LBRACE, Value: string(l.ch), Pos: tok.Pos}
 	case '}':
+		if n := len(l.interps); n > 0 {
+			if l.interps[n-1] == 0 {
+				// End of an embedded expression; the string continues
+				l.interps = l.interps[:n-1]
+				l.readChar() // consume '}'
+				tok.Value, tok.Kind = l.readString(STRING_END, STRING_MID)
+				return tok // readString() advances position
+			}
+			l.interps[n-1]--
+		}
 		tok = Token{Kind: RBRACE, Value: string(l.ch), Pos: tok.Pos}
 	case '[':
 		tok = Token{Kind: LBRACKET, Value: string(l.ch), Pos: tok.Pos}
 	case ']':
 		tok = Token{Kind: RBRACKET, Value: string(l.ch), Pos: tok.Pos}
+	case '\'':
+		tok.Value, tok.Kind = l.readCharLit()
+		return tok // readCharLit() advances position
+	case '`':
+		tok.Value, tok.Kind = l.readRawString()
+		return tok // readRawString() advances position
 	case '"':
-		tok.Value = l.readString()
-		tok.Kind = STRING
+		if strings.HasPrefix(l.input[l.pos:], `"""`) {
+			tok.Value, tok.Kind = l.readTextBlock()
+			return tok // readTextBlock() advances position
+		}
+		l.readChar() // move past opening quote
+		tok.Value, tok.Kind = l.readString(STRING, STRING_START)
 		return tok // readString() advances position
 	case 0:
 		tok = Token{Kind: EOF, Value: "", Pos: tok.Pos}
 	default:
-		if isLetter(l.ch) {
+		if isLetter(l.ch) || l.ch == '_' {
 			tok.Value = l.readIdentifier()
 			tok.Kind = lookupIdent(tok.Value)
 			return tok // readIdentifier() advances position
 		} else if isDigit(l.ch) {
-			tok.Value = l.readNumber()
-			if strings.Contains(tok.Value, ".") {
+			value, float, ok := l.readNumber()
+			tok.Value = value
+			switch {
+			case !ok:
+				tok.Kind = ILLEGAL
+			case float:
 				tok.Kind = FLOAT
-			} else {
+			default:
 				tok.Kind = INT
 			}
 			return tok // readNumber() advances position
@@ -277,6 +367,16 @@ func (l *Lexer) NextToken() Token {
 	return tok
 }
 
+// readOperator returns the token for an arithmetic operator, or for its
+// compound assignment form when it is followed by '='
+func (l *Lexer) readOperator(op, assign TokenKind, pos diag.Position) Token {
+	if l.peekChar() == '=' {
+		l.readChar()
+		return Token{Kind: assign, Value: string(assign), Pos: pos}
+	}
+	return Token{Kind: op, Value: string(op), Pos: pos}
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
@@ -319,79 +419,324 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.pos]
 }
 
-func (l *Lexer) readNumber() string {
+// numberSuffixes are the types a numeric literal can be given by a suffix, as
+// in 10u8 or 2.5f32
+var numberSuffixes = map[string]bool{
+	"i8": true, "i32": true, "i64": true,
+	"u8": true, "u16": true, "u32": true, "u64": true,
+	"f32": true, "f64": true,
+}
+
+// readNumber reads a numeric literal: decimal, or hexadecimal, binary or octal
+// after a 0x, 0b or 0o prefix, with single underscores between digits, a
+// fraction and exponent for decimals, and an optional type suffix. It reports
+// whether the literal is a float and whether it is well formed.
+func (l *Lexer) readNumber() (string, bool, bool) {
 	position := l.pos
-	for isDigit(l.ch) {
-		l.readChar()
+	digit := isDigit
+	decimal := true
+	if l.ch == '0' {
+		switch l.peekChar() {
+		case 'x', 'X':
+			digit, decimal = isHexDigit, false
+		case 'b', 'B':
+			digit, decimal = isBinaryDigit, false
+		case 'o', 'O':
+			digit, decimal = isOctalDigit, false
+		}
+		if !decimal {
+			l.readChar() // consume '0'
+			l.readChar() // consume prefix
+		}
 	}
+	ok := l.readDigits(digit)
+	float := false
 
 	// Handle float
-	if l.ch == '.' && isDigit(l.peekChar()) {
+	if decimal && l.ch == '.' && isDigit(l.peekChar()) {
+		float = true
 		l.readChar() // consume '.'
-		for isDigit(l.ch) {
+		ok = l.readDigits(isDigit) && ok
+	}
+	if decimal && (l.ch == 'e' || l.ch == 'E') {
+		float = true
+		l.readChar() // consume 'e'
+		if l.ch == '+' || l.ch == '-' {
 			l.readChar()
 		}
+		ok = l.readDigits(isDigit) && ok
 	}
 
-	return l.input[position:l.pos]
+	suffix := l.pos
+	for isLetter(l.ch) || isDigit(l.ch) || l.ch == '_' {
+		l.readChar()
+	}
+	if suffix := l.input[suffix:l.pos]; suffix != "" {
+		switch {
+		case !numberSuffixes[suffix]:
+			ok = false
+		case suffix[0] == 'f':
+			ok = ok && decimal
+			float = true
+		default:
+			ok = ok && !float
+		}
+	}
+
+	return l.input[position:l.pos], float, ok
 }
 
-func (l *Lexer) readString() string {
-	l.readChar() // move past opening quote
+// readDigits reads at least one digit, with single underscores between
+// digits, and reports whether the digits are well formed
+func (l *Lexer) readDigits(digit func(byte) bool) bool {
+	if !digit(l.ch) {
+		return false
+	}
+	for digit(l.ch) || l.ch == '_' && digit(l.peekChar()) {
+		l.readChar()
+	}
+	return true
+}
+
+// SplitNumber splits a well-formed numeric literal into its digits without
+// underscores, their base and its type suffix, as in 0xff_ffu32 giving
+// "ffff", 16 and "u32". The digits of a decimal may include a fraction and
+// an exponent.
+func SplitNumber(lit string) (string, int, string) {
+	base := 10
+	if len(lit) > 2 && lit[0] == '0' {
+		switch lit[1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+		if base != 10 {
+			lit = lit[2:]
+		}
+	}
+
+	end := strings.IndexAny(lit, "iu")
+	if base != 16 {
+		if f := strings.IndexByte(lit, 'f'); f >= 0 && (end < 0 || f < end) {
+			end = f
+		}
+	}
+	suffix := ""
+	if end >= 0 {
+		lit, suffix = lit[:end], lit[end:]
+	}
+	return strings.ReplaceAll(lit, "_", ""), base, suffix
+}
 
+// readString reads the characters of a string literal up to the closing quote,
+// returning them as a token of kind end, or up to a ${ starting an embedded
+// expression, returning them as a token of kind interp. A $ is written \$
+// when followed by {. A segment with an invalid escape is read to its end and
+// returned as an ILLEGAL token holding the first such escape.
+func (l *Lexer) readString(end, interp TokenKind) (string, TokenKind) {
 	var result strings.Builder
+	invalid := ""
+	kind := end
 	for l.ch != '"' && l.ch != 0 {
+		if l.ch == '$' && l.peekChar() == '{' {
+			l.readChar() // consume '$'
+			l.readChar() // consume '{'
+			l.interps = append(l.interps, 0)
+			kind = interp
+			break
+		}
 		if l.ch == '\\' {
-			l.readChar()
-			switch l.ch {
-			case 'n':
-				result.WriteByte('\n')
-			case 't':
-				result.WriteByte('\t')
-			case 'r':
-				result.WriteByte('\r')
-			case '"':
-				result.WriteByte('"')
-			case '\\':
-				result.WriteByte('\\')
-			case 'u':
-				// Unicode escape \uXXXX
-				l.readChar()
-				hex := l.readHex(4)
-				if code, err := strconv.ParseUint(hex, 16, 16); err == nil {
-					result.WriteRune(rune(code))
-				}
-				continue // readHex already advanced position
-			case 'U':
-				// Unicode escape \UXXXXXXXX
-				l.readChar()
-				hex := l.readHex(8)
-				if code, err := strconv.ParseUint(hex, 16, 32); err == nil {
-					result.WriteRune(rune(code))
-				}
-				continue // readHex already advanced position
-			case 'x':
-				// Hex escape \xXX
-				l.readChar()
-				hex := l.readHex(2)
-				if code, err := strconv.ParseUint(hex, 16, 8); err == nil {
-					result.WriteByte(byte(code))
-				}
-				continue // readHex already advanced position
-			default:
-				result.WriteByte(l.ch)
+			start := l.pos
+			escaped, ok := l.readEscape()
+			if !ok && invalid == "" {
+				invalid = l.input[start:l.pos]
 			}
-		} else {
-			result.WriteByte(l.ch)
+			result.WriteString(escaped)
+			continue // readEscape() advances position
 		}
+		result.WriteByte(l.ch)
 		l.readChar()
 	}
 
-	if l.ch == '"' {
+	if kind == end && l.ch == '"' {
 		l.readChar() // consume closing quote
 	}
 
-	return result.String()
+	if invalid != "" {
+		return invalid, ILLEGAL
+	}
+	return result.String(), kind
+}
+
+// readEscape reads an escape sequence starting at a backslash and returns the
+// text it denotes. It reports whether the escape is valid; an unknown escape
+// denotes the escaped character, as in \$.
+func (l *Lexer) readEscape() (string, bool) {
+	l.readChar() // consume '\\'
+	ch := l.ch
+	l.readChar()
+	switch ch {
+	case 'n':
+		return "\n", true
+	case 't':
+		return "\t", true
+	case 'r':
+		return "\r", true
+	case '0':
+		return "\x00", true
+	case '"', '\'', '\\', '$':
+		return string(ch), true
+	case 'u':
+		if l.ch == '{' {
+			// Unicode escape \u{X...} of any code point
+			l.readChar()
+			hex := l.readHex(6)
+			if l.ch != '}' {
+				return "", false
+			}
+			l.readChar() // consume '}'
+			code, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", false
+			}
+			return string(rune(code)), true
+		}
+		// Unicode escape \uXXXX
+		hex := l.readHex(4)
+		code, err := strconv.ParseUint(hex, 16, 16)
+		if err != nil || len(hex) != 4 || !utf8.ValidRune(rune(code)) {
+			return "", false
+		}
+		return string(rune(code)), true
+	case 'U':
+		// Unicode escape \UXXXXXXXX
+		hex := l.readHex(8)
+		code, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 8 || !utf8.ValidRune(rune(code)) {
+			return "", false
+		}
+		return string(rune(code)), true
+	case 'x':
+		// Hex escape \xXX
+		hex := l.readHex(2)
+		code, err := strconv.ParseUint(hex, 16, 8)
+		if err != nil || len(hex) != 2 {
+			return "", false
+		}
+		return string([]byte{byte(code)}), true
+	case 0:
+		return "", false
+	}
+	return string(ch), false
+}
+
+// readCharLit reads a character literal, returning the character it denotes
+// encoded in UTF-8. Escapes are those of strings.
+func (l *Lexer) readCharLit() (string, TokenKind) {
+	start := l.pos
+	l.readChar() // move past opening quote
+	var value string
+	ok := true
+	if l.ch == '\\' {
+		hex := l.peekChar() == 'x'
+		value, ok = l.readEscape()
+		if hex && ok {
+			// A hex escape in a char literal is a code point, not a byte
+			value = string(rune(value[0]))
+		}
+	} else if l.ch != '\'' && l.ch != '\n' && l.ch != 0 {
+		_, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		value = l.input[l.pos : l.pos+size]
+		for i := 0; i < size; i++ {
+			l.readChar()
+		}
+	}
+
+	if r, size := utf8.DecodeRuneInString(value); !ok || l.ch != '\'' || size == 0 || size != len(value) || r == utf8.RuneError && size == 1 {
+		// Skip the rest of a malformed literal on its line
+		for l.ch != '\'' && l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		if l.ch == '\'' {
+			l.readChar()
+		}
+		return l.input[start:l.pos], ILLEGAL
+	}
+	l.readChar() // consume closing quote
+	return value, CHAR
+}
+
+// readRawString reads a string between backticks verbatim, line breaks
+// included. There are no escapes, so a raw string cannot contain a backtick.
+func (l *Lexer) readRawString() (string, TokenKind) {
+	start := l.pos
+	l.readChar() // move past opening backtick
+	for l.ch != '`' && l.ch != 0 {
+		l.readChar()
+	}
+	if l.ch == 0 {
+		return l.input[start:l.pos], ILLEGAL
+	}
+	value := l.input[start+1 : l.pos]
+	l.readChar() // consume closing backtick
+	return strings.ReplaceAll(value, "\r", ""), STRING
+}
+
+// readTextBlock reads a string between triple quotes verbatim, line breaks
+// included. When the opening quotes end their line, that line break is
+// dropped and so is the indentation common to the lines, so that the block
+// can be indented with the code around it; see trimIndent.
+func (l *Lexer) readTextBlock() (string, TokenKind) {
+	start := l.pos
+	for i := 0; i < 3; i++ {
+		l.readChar() // consume opening quotes
+	}
+	position := l.pos
+	for l.ch != 0 && !strings.HasPrefix(l.input[l.pos:], `"""`) {
+		l.readChar()
+	}
+	if l.ch == 0 {
+		return l.input[start:l.pos], ILLEGAL
+	}
+	value := strings.ReplaceAll(l.input[position:l.pos], "\r", "")
+	for i := 0; i < 3; i++ {
+		l.readChar() // consume closing quotes
+	}
+	if rest, ok := strings.CutPrefix(value, "\n"); ok {
+		return trimIndent(rest), STRING
+	}
+	return value, STRING
+}
+
+// trimIndent removes the leading spaces and tabs common to the lines of text
+// that are not blank. A last line of only spaces and tabs, the one of the
+// closing quotes, also counts and is dropped with its line break.
+func trimIndent(text string) string {
+	lines := strings.Split(text, "\n")
+	last := lines[len(lines)-1]
+	closing := strings.TrimLeft(last, " \t") == ""
+	indent := -1
+	if closing {
+		indent = len(last)
+		lines = lines[:len(lines)-1]
+	}
+	for _, line := range lines {
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if n < len(line) && (indent < 0 || n < indent) {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) < indent {
+			lines[i] = ""
+		} else if indent > 0 {
+			lines[i] = line[indent:]
+		}
+	}
+	return strings.Join(lines, "\n")
 }
 
 func (l *Lexer) readHex(count int) string {
@@ -410,6 +755,14 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isBinaryDigit(ch byte) bool {
+	return ch == '0' || ch == '1'
+}
+
+func isOctalDigit(ch byte) bool {
+	return '0' <= ch && ch <= '7'
+}
+
 func isHexDigit(ch byte) bool {
 	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
 }
@@ -57,3 +57,208 @@ fun main() : none {
 		t.Fatalf("expected some keywords, got 0")
 	}
 }
+
+func TestLexer_AssignOperators(t *testing.T) {
+	l := NewLexer("x = 1 x += 1 x -= 1 x *= 1 x /= 1 x %= 1 a -> b", "<mem>")
+
+	var got []TokenKind
+	for tok := l.NextToken(); tok.Kind != EOF; tok = l.NextToken() {
+		if tok.Kind != IDENT && tok.Kind != INT {
+			got = append(got, tok.Kind)
+		}
+	}
+	want := []TokenKind{ASSIGN, PLUS_ASSIGN, MINUS_ASSIGN, MUL_ASSIGN, DIV_ASSIGN, MOD_ASSIGN, ARROW}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d operators, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("operator %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestLexer_Ranges(t *testing.T) {
+	l := NewLexer("0..10 a..=b 1.5 x.y", "<mem>")
+
+	want := []TokenKind{INT, DOTDOT, INT, IDENT, DOTDOTEQ, IDENT, FLOAT, IDENT, DOT, IDENT}
+	for i, kind := range want {
+		if tok := l.NextToken(); tok.Kind != kind {
+			t.Fatalf("token %d: expected %s, got %s (%q)", i, kind, tok.Kind, tok.Value)
+		}
+	}
+}
+
+func TestLexer_BitwiseOperators(t *testing.T) {
+	l := NewLexer("a & b | c ^ d ~e << >> &= |= ^= <<= >>= && || < > <= >=", "<mem>")
+
+	var got []TokenKind
+	for tok := l.NextToken(); tok.Kind != EOF; tok = l.NextToken() {
+		if tok.Kind != IDENT {
+			got = append(got, tok.Kind)
+		}
+	}
+	want := []TokenKind{BIT_AND, BIT_OR, BIT_XOR, BIT_NOT, SHL, SHR,
+		AND_ASSIGN, OR_ASSIGN, XOR_ASSIGN, SHL_ASSIGN, SHR_ASSIGN, AND, OR, LT, GT, LE, GE}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d operators, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("operator %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestLexer_Numbers(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  TokenKind
+	}{
+		{"0xFF_FF", INT},
+		{"0b1010", INT},
+		{"0o755", INT},
+		{"1_000_000", INT},
+		{"10u8", INT},
+		{"0xFFi64", INT},
+		{"1.5e-3", FLOAT},
+		{"1E10", FLOAT},
+		{"2.0f32", FLOAT},
+		{"2f64", FLOAT},
+		{"0x", ILLEGAL},
+		{"0b102", ILLEGAL},
+		{"1__0", ILLEGAL},
+		{"1_", ILLEGAL},
+		{"1e", ILLEGAL},
+		{"1.5i32", ILLEGAL},
+		{"0x1f32", INT},
+		{"0o7f32", ILLEGAL},
+		{"10q", ILLEGAL},
+	}
+	for _, tt := range tests {
+		tok := NewLexer(tt.input, "<mem>").NextToken()
+		if tok.Kind != tt.kind || tok.Value != tt.input {
+			t.Errorf("%s: expected %s, got %s (%q)", tt.input, tt.kind, tok.Kind, tok.Value)
+		}
+	}
+
+	digits, base, suffix := SplitNumber("0xff_ffu32")
+	if digits != "ffff" || base != 16 || suffix != "u32" {
+		t.Fatalf("expected ffff, 16, u32, got %s, %d, %s", digits, base, suffix)
+	}
+	digits, base, suffix = SplitNumber("1_000.5e-3f32")
+	if digits != "1000.5e-3" || base != 10 || suffix != "f32" {
+		t.Fatalf("expected 1000.5e-3, 10, f32, got %s, %d, %s", digits, base, suffix)
+	}
+}
+
+func TestLexer_InterpolatedStrings(t *testing.T) {
+	l := NewLexer(`"a${x + "b${y}"}c${ {} }" "\${z}"`, "<mem>")
+
+	want := []struct {
+		kind  TokenKind
+		value string
+	}{
+		{STRING_START, "a"}, {IDENT, "x"}, {PLUS, "+"},
+		{STRING_START, "b"}, {IDENT, "y"}, {STRING_END, ""},
+		{STRING_MID, "c"}, {LBRACE, "{"}, {RBRACE, "}"}, {STRING_END, ""},
+		{STRING, "${z}"}, {EOF, ""},
+	}
+	for i, w := range want {
+		if tok := l.NextToken(); tok.Kind != w.kind || tok.Value != w.value {
+			t.Fatalf("token %d: expected %s %q, got %s %q", i, w.kind, w.value, tok.Kind, tok.Value)
+		}
+	}
+}
+
+func TestLexer_InvalidStringEscapes(t *testing.T) {
+	l := NewLexer(`"\q" "a\x4" "${x}\u12 y" ok`, "<mem>")
+
+	want := []struct {
+		kind  TokenKind
+		value string
+	}{
+		{ILLEGAL, `\q`}, {ILLEGAL, `\x4`},
+		{STRING_START, ""}, {IDENT, "x"}, {ILLEGAL, `\u12`},
+		{IDENT, "ok"}, {EOF, ""},
+	}
+	for i, w := range want {
+		if tok := l.NextToken(); tok.Kind != w.kind || tok.Value != w.value {
+			t.Fatalf("token %d: expected %s %q, got %s %q", i, w.kind, w.value, tok.Kind, tok.Value)
+		}
+	}
+}
+
+func TestLexer_RawStrings(t *testing.T) {
+	input := "`a\\n${b}\n  c` \"\"\"\n    x\n      \"y\"\n    \"\"\" z"
+	l := NewLexer(input, "<mem>")
+
+	raw := l.NextToken()
+	if raw.Kind != STRING || raw.Value != "a\\n${b}\n  c" {
+		t.Fatalf("expected raw string, got %s %q", raw.Kind, raw.Value)
+	}
+	block := l.NextToken()
+	if block.Kind != STRING || block.Value != "x\n  \"y\"" {
+		t.Fatalf("expected text block, got %s %q", block.Kind, block.Value)
+	}
+	if block.Pos.Line != 2 || block.Pos.Column != 6 {
+		t.Fatalf("expected text block at 2:6, got %d:%d", block.Pos.Line, block.Pos.Column)
+	}
+	z := l.NextToken()
+	if z.Kind != IDENT || z.Pos.Line != 5 || z.Pos.Column != 9 {
+		t.Fatalf("expected z at 5:9, got %s at %d:%d", z.Kind, z.Pos.Line, z.Pos.Column)
+	}
+
+	if tok := NewLexer("`open", "<mem>").NextToken(); tok.Kind != ILLEGAL {
+		t.Fatalf("expected ILLEGAL for unterminated raw string, got %s", tok.Kind)
+	}
+	if tok := NewLexer(`"""open`, "<mem>").NextToken(); tok.Kind != ILLEGAL {
+		t.Fatalf("expected ILLEGAL for unterminated text block, got %s", tok.Kind)
+	}
+}
+
+func TestLexer_CharLiterals(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  TokenKind
+		value string
+	}{
+		{`'a'`, CHAR, "a"},
+		{`'\n'`, CHAR, "\n"},
+		{`'\''`, CHAR, "'"},
+		{`'\0'`, CHAR, "\x00"},
+		{`'\x41'`, CHAR, "A"},
+		{`'\x7F'`, CHAR, "\x7f"},
+		{`'\x80'`, CHAR, "\u0080"},
+		{`'\xFF'`, CHAR, "ÿ"},
+		{`'é'`, CHAR, "é"},
+		{`'\u{1F47E}'`, CHAR, "👾"},
+		{`'\uFFFD'`, CHAR, "\uFFFD"},
+		{"'\uFFFD'", CHAR, "\uFFFD"},
+		{`''`, ILLEGAL, `''`},
+		{`'ab'`, ILLEGAL, `'ab'`},
+		{`'\q'`, ILLEGAL, `'\q'`},
+		{`'\xG0'`, ILLEGAL, `'\xG0'`},
+		{`'\u{110000}'`, ILLEGAL, `'\u{110000}'`},
+		{`'\uD800'`, ILLEGAL, `'\uD800'`},
+		{"'\xff'", ILLEGAL, "'\xff'"},
+		{`'a`, ILLEGAL, `'a`},
+	}
+	for _, tt := range tests {
+		tok := NewLexer(tt.input, "<mem>").NextToken()
+		if tok.Kind != tt.kind || tok.Value != tt.value {
+			t.Errorf("%s: expected %s %q, got %s %q", tt.input, tt.kind, tt.value, tok.Kind, tok.Value)
+		}
+	}
+
+	l := NewLexer(`"\u{1F47E}\x41\$" 'x' y`, "<mem>")
+	if tok := l.NextToken(); tok.Kind != STRING || tok.Value != "👾A$" {
+		t.Fatalf("expected escaped string, got %s %q", tok.Kind, tok.Value)
+	}
+	if tok := l.NextToken(); tok.Kind != CHAR {
+		t.Fatalf("expected CHAR, got %s", tok.Kind)
+	}
+	if tok := l.NextToken(); tok.Kind != IDENT || tok.Pos.Column != 23 {
+		t.Fatalf("expected y at column 23, got %s at %d", tok.Kind, tok.Pos.Column)
+	}
+}
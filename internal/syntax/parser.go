@@ -1,6 +1,8 @@
 package syntax
 
 import (
+	"strconv"
+
 	"jmpeax.com/guayavita/gvc/internal/diag"
 )
 
@@ -10,6 +12,7 @@ type Parser struct {
 	peekToken   Token
 	diagnostics []diag.Diagnostic
 	hasError    bool
+	noStructLit bool // set while parsing control clauses, where '{' opens the body
 }
 
 // ParseFile parses a Guayavita source file and returns the AST and any diagnostics
@@ -79,10 +82,12 @@ func (p *Parser) parseFile() *File {
 	// Parse package declaration
 	if p.curToken.Kind == PACKAGE {
 		p.nextToken() // consume 'package'
-		if p.expectToken(IDENT) {
-			file.Package = p.curToken.Value
-			p.nextToken()
-		}
+		file.Package = p.parsePackagePath()
+	}
+
+	// Parse imports
+	for p.curToken.Kind == IMPORT && !p.hasError {
+		file.Imports = append(file.Imports, p.parseImportDecl()...)
 	}
 
 	// Parse declarations
@@ -96,38 +101,184 @@ func (p *Parser) parseFile() *File {
 	return file
 }
 
+// parsePackagePath parses a dotted package path: ident { "." ident }
+func (p *Parser) parsePackagePath() string {
+	if !p.expectToken(IDENT) {
+		return ""
+	}
+	path := p.curToken.Value
+	p.nextToken()
+
+	for p.curToken.Kind == DOT {
+		p.nextToken() // consume '.'
+		if !p.expectToken(IDENT) {
+			return path
+		}
+		path += "." + p.curToken.Value
+		p.nextToken()
+	}
+
+	return path
+}
+
+// parseImportDecl parses an import declaration. The grouped form yields one
+// ImportDecl per clause.
+func (p *Parser) parseImportDecl() []*ImportDecl {
+	p.nextToken() // consume 'import'
+
+	if p.curToken.Kind != LBRACE {
+		clause := p.parseImportClause()
+		if clause == nil {
+			return nil
+		}
+		return []*ImportDecl{clause}
+	}
+
+	p.nextToken() // consume '{'
+	clauses := []*ImportDecl{}
+	for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
+		clause := p.parseImportClause()
+		if clause != nil {
+			clauses = append(clauses, clause)
+		}
+
+		if p.curToken.Kind == COMMA {
+			p.nextToken()
+		} else if p.curToken.Kind != RBRACE {
+			p.error("expected ',' or '}' in import group")
+			break
+		}
+	}
+
+	if !p.expectToken(RBRACE) {
+		return clauses
+	}
+	p.nextToken() // consume '}'
+
+	return clauses
+}
+
+func (p *Parser) parseImportClause() *ImportDecl {
+	pos := p.curToken.Pos
+	path := p.parsePackagePath()
+	if path == "" {
+		return nil
+	}
+
+	decl := &ImportDecl{
+		Path: path,
+		Pos_: pos,
+	}
+
+	// Optional symbol list
+	if p.curToken.Kind == LBRACE {
+		p.nextToken() // consume '{'
+		for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
+			if !p.expectToken(IDENT) {
+				return nil
+			}
+			decl.Symbols = append(decl.Symbols, p.curToken.Value)
+			p.nextToken()
+
+			if p.curToken.Kind == COMMA {
+				p.nextToken()
+			} else if p.curToken.Kind != RBRACE {
+				p.error("expected ',' or '}' in import symbol list")
+				return nil
+			}
+		}
+
+		if !p.expectToken(RBRACE) {
+			return nil
+		}
+		p.nextToken() // consume '}'
+	}
+
+	// Optional alias
+	if p.curToken.Kind == AS {
+		p.nextToken() // consume 'as'
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+		decl.Alias = p.curToken.Value
+		p.nextToken()
+	}
+
+	return decl
+}
+
 func (p *Parser) parseDecl() Decl {
 	switch p.curToken.Kind {
 	case DEF:
 		return p.parseVarDecl()
 	case FUN:
 		return p.parseFunDecl()
+	case TYPE:
+		return p.parseTypeDecl()
+	case IMPL:
+		return p.parseImplDecl()
+	case EXPORT:
+		return p.parseExportedDecl()
 	default:
-		p.error("expected declaration, got " + string(p.curToken.Kind))
+		if p.curToken.Kind == IMPORT {
+			p.error("imports must appear before other declarations")
+		} else {
+			p.error("expected declaration, got " + string(p.curToken.Kind))
+		}
 		p.nextToken() // skip invalid token
 		return nil
 	}
 }
 
+// parseExportedDecl parses a top-level def, fun or type declaration marked
+// export, which other packages can refer to
+func (p *Parser) parseExportedDecl() Decl {
+	p.nextToken() // consume 'export'
+	switch p.curToken.Kind {
+	case DEF:
+		if decl := p.parseVarDecl(); decl != nil {
+			decl.Exported = true
+			return decl
+		}
+	case FUN:
+		if decl := p.parseFunDecl(); decl != nil {
+			decl.Exported = true
+			return decl
+		}
+	case TYPE:
+		if decl := p.parseTypeDecl(); decl != nil {
+			decl.Exported = true
+			return decl
+		}
+	default:
+		p.error("expected def, fun or type after export, got " + string(p.curToken.Kind))
+	}
+	return nil
+}
+
 func (p *Parser) parseVarDecl() *VarDecl {
 	pos := p.curToken.Pos
 	p.nextToken() // consume 'def'
 
-	if !p.expectToken(IDENT) {
-		return nil
+	var name string
+	var names []string
+	if p.curToken.Kind == LPAREN {
+		if names = p.parseDestructuring(); names == nil {
+			return nil
+		}
+	} else {
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+		name = p.curToken.Value
+		p.nextToken()
 	}
 
-	name := p.curToken.Value
-	p.nextToken()
-
-	var typeName string
+	var typ TypeExpr
 	if p.curToken.Kind == COLON {
 		p.nextToken() // consume ':'
-		if p.curToken.Kind == IDENT || p.isTypeKeyword(p.curToken.Kind) {
-			typeName = p.curToken.Value
-			p.nextToken()
-		} else {
-			p.error("expected type identifier")
+		if typ = p.parseType(); typ == nil {
+			return nil
 		}
 	}
 
@@ -139,11 +290,39 @@ func (p *Parser) parseVarDecl() *VarDecl {
 	init := p.parseExpr()
 
 	return &VarDecl{
-		Name: name,
-		Type: typeName,
-		Init: init,
-		Pos_: pos,
+		Name:  name,
+		Names: names,
+		Type:  typ,
+		Init:  init,
+		Pos_:  pos,
+	}
+}
+
+// parseDestructuring parses the names a tuple is destructured into: (q, r)
+func (p *Parser) parseDestructuring() []string {
+	p.nextToken() // consume '('
+	var names []string
+	for {
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+		names = append(names, p.curToken.Value)
+		p.nextToken()
+		if p.curToken.Kind != COMMA {
+			break
+		}
+		p.nextToken() // consume ','
 	}
+	if !p.expectToken(RPAREN) {
+		return nil
+	}
+	p.nextToken() // consume ')'
+
+	if len(names) < 2 {
+		p.error("destructuring requires at least two names")
+		return nil
+	}
+	return names
 }
 
 func (p *Parser) parseFunDecl() *FunDecl {
@@ -157,9 +336,39 @@ func (p *Parser) parseFunDecl() *FunDecl {
 	name := p.curToken.Value
 	p.nextToken()
 
-	if !p.expectToken(LPAREN) {
+	// Optional type parameters: fun max<T>(...)
+	var typeParams []string
+	var bounds []TypeExpr
+	if p.curToken.Kind == LT {
+		if typeParams, bounds = p.parseTypeParams(); typeParams == nil {
+			return nil
+		}
+	}
+
+	params, returnType := p.parseSignature()
+	if returnType == nil {
 		return nil
 	}
+
+	body := p.parseBlock()
+
+	return &FunDecl{
+		Name:       name,
+		TypeParams: typeParams,
+		Bounds:     bounds,
+		Params:     params,
+		Type:       returnType,
+		Body:       body,
+		Pos_:       pos,
+	}
+}
+
+// parseSignature parses the parameter list and result type of a function:
+// (a: i32, b: i32) : i32. The result type is nil on errors.
+func (p *Parser) parseSignature() ([]Param, TypeExpr) {
+	if !p.expectToken(LPAREN) {
+		return nil, nil
+	}
 	p.nextToken() // consume '('
 
 	params := []Param{}
@@ -178,27 +387,37 @@ func (p *Parser) parseFunDecl() *FunDecl {
 	}
 
 	if !p.expectToken(RPAREN) {
-		return nil
+		return nil, nil
 	}
 	p.nextToken() // consume ')'
 
 	if !p.expectToken(COLON) {
-		return nil
+		return nil, nil
 	}
 	p.nextToken() // consume ':'
 
-	var returnType string
-	if p.curToken.Kind == IDENT || p.curToken.Kind == NONE {
-		returnType = p.curToken.Value
-		p.nextToken()
-	} else {
-		p.error("expected type identifier")
+	return params, p.parseType()
+}
+
+// parseFuncLit parses an anonymous function: fun(x: i32) : i32 { ... }
+func (p *Parser) parseFuncLit() Expr {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'fun'
+
+	params, returnType := p.parseSignature()
+	if returnType == nil {
+		return &BasicLit{Kind: "INVALID", Value: "", Pos_: pos}
 	}
 
+	saved := p.noStructLit
+	p.noStructLit = false
+	defer func() { p.noStructLit = saved }()
 	body := p.parseBlock()
+	if body == nil {
+		return &BasicLit{Kind: "INVALID", Value: "", Pos_: pos}
+	}
 
-	return &FunDecl{
-		Name:   name,
+	return &FuncLit{
 		Params: params,
 		Type:   returnType,
 		Body:   body,
@@ -206,6 +425,112 @@ func (p *Parser) parseFunDecl() *FunDecl {
 	}
 }
 
+func (p *Parser) parseImplDecl() *ImplDecl {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'impl'
+
+	decl := &ImplDecl{Pos_: pos}
+
+	// Optional type parameters: impl<T> Box { ... }. Their bounds are those
+	// of the type.
+	if p.curToken.Kind == LT {
+		var bounds []TypeExpr
+		if decl.TypeParams, bounds = p.parseTypeParams(); decl.TypeParams == nil {
+			return nil
+		}
+		if bounds != nil {
+			p.error("type parameters of an impl block cannot have bounds")
+			return nil
+		}
+	}
+
+	if !p.expectToken(IDENT) {
+		return nil
+	}
+	// impl Shape for Circle, where the interface may be imported
+	if p.peekToken.Kind == FOR || p.peekToken.Kind == DOT {
+		if decl.Interface = p.parseType(); decl.Interface == nil {
+			return nil
+		}
+		if !p.expectToken(FOR) {
+			return nil
+		}
+		p.nextToken() // consume 'for'
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+	}
+	decl.Type = p.curToken.Value
+	p.nextToken()
+
+	if !p.expectToken(LBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '{'
+
+	for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
+		if !p.expectToken(FUN) {
+			return nil
+		}
+		if method := p.parseFunDecl(); method != nil {
+			decl.Methods = append(decl.Methods, method)
+		}
+	}
+
+	if !p.expectToken(RBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '}'
+
+	return decl
+}
+
+// parseTypeParams parses a type parameter list, where each parameter may be
+// bounded by an interface: <T, U: Shape>. Bounds are nil when no parameter
+// has one. It returns nil params on error.
+func (p *Parser) parseTypeParams() ([]string, []TypeExpr) {
+	p.nextToken() // consume '<'
+
+	params := []string{}
+	var bounds []TypeExpr
+	for p.curToken.Kind != GT && p.curToken.Kind != EOF && !p.hasError {
+		if !p.expectToken(IDENT) {
+			return nil, nil
+		}
+		params = append(params, p.curToken.Value)
+		p.nextToken()
+		if p.curToken.Kind == COLON {
+			p.nextToken() // consume ':'
+			bound := p.parseType()
+			if bound == nil {
+				return nil, nil
+			}
+			if bounds == nil {
+				bounds = make([]TypeExpr, len(params)-1, len(params))
+			}
+			bounds = append(bounds, bound)
+		} else if bounds != nil {
+			bounds = append(bounds, nil)
+		}
+		if p.curToken.Kind == COMMA {
+			p.nextToken()
+		} else if p.curToken.Kind != GT {
+			p.error("expected ',' or '>' in type parameter list")
+			return nil, nil
+		}
+	}
+	if len(params) == 0 {
+		p.error("expected type parameter")
+		return nil, nil
+	}
+	if !p.expectToken(GT) {
+		return nil, nil
+	}
+	p.nextToken() // consume '>'
+
+	return params, bounds
+}
+
 func (p *Parser) parseParam() *Param {
 	if !p.expectToken(IDENT) {
 		return nil
@@ -215,38 +540,429 @@ func (p *Parser) parseParam() *Param {
 	name := p.curToken.Value
 	p.nextToken()
 
+	// The method receiver is written without a type
+	if name == ReceiverName && p.curToken.Kind != COLON {
+		return &Param{Name: name, Pos_: pos}
+	}
+
 	if !p.expectToken(COLON) {
 		return nil
 	}
 	p.nextToken() // consume ':'
 
-	if p.curToken.Kind == IDENT || p.isTypeKeyword(p.curToken.Kind) {
-		typeName := p.curToken.Value
-		p.nextToken()
+	typ := p.parseType()
+	if typ == nil {
+		return nil
+	}
 
-		return &Param{
-			Name: name,
-			Type: typeName,
-			Pos_: pos,
-		}
-	} else {
+	return &Param{
+		Name: name,
+		Type: typ,
+		Pos_: pos,
+	}
+}
+
+// parseType parses a type reference: a primitive or declared type name,
+// optionally qualified by a package (pkg.Name) and instantiated with type
+// arguments (Box<T>). It returns nil on error.
+func (p *Parser) parseType() TypeExpr {
+	if p.curToken.Kind == LPAREN {
+		return p.parseTupleType()
+	}
+	if p.curToken.Kind == LBRACKET {
+		return p.parseSliceType()
+	}
+	if p.curToken.Kind == FUN {
+		return p.parseFuncType()
+	}
+	if p.curToken.Kind != IDENT && !p.isTypeKeyword(p.curToken.Kind) {
 		p.error("expected type identifier")
 		return nil
 	}
+	typ := &NamedType{Name: p.curToken.Value, Pos_: p.curToken.Pos}
+	p.nextToken()
+
+	if p.curToken.Kind == DOT {
+		p.nextToken() // consume '.'
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+		typ.Pkg, typ.Name = typ.Name, p.curToken.Value
+		p.nextToken()
+	}
+
+	// Optional type arguments
+	if p.curToken.Kind == LT {
+		p.nextToken() // consume '<'
+		for p.curToken.Kind != GT && p.curToken.Kind != SHR && p.curToken.Kind != EOF && !p.hasError {
+			arg := p.parseType()
+			if arg == nil {
+				return nil
+			}
+			typ.Args = append(typ.Args, arg)
+			if p.curToken.Kind == COMMA {
+				p.nextToken()
+			} else if p.curToken.Kind != GT && p.curToken.Kind != SHR {
+				p.error("expected ',' or '>' in type argument list")
+				return nil
+			}
+		}
+		if len(typ.Args) == 0 {
+			p.error("expected type argument")
+			return nil
+		}
+		if !p.consumeClosingAngle() {
+			return nil
+		}
+	}
+
+	var result TypeExpr = typ
+	if p.curToken.Kind == LBRACKET {
+		result = p.parseArrayType(result)
+		if result == nil {
+			return nil
+		}
+	}
+
+	// Optional suffix
+	for p.curToken.Kind == QUESTION {
+		result = &OptionalType{Elem: result, Pos_: typ.Pos_}
+		p.nextToken()
+	}
+	return result
 }
 
-func (p *Parser) parseBlock() *Block {
-	pos := p.curToken.Pos
+// consumeClosingAngle consumes the '>' closing a type argument list. The
+// lexer reads '>>' as a shift, so in Box<Box<i32>> the inner list takes only
+// the first '>' and leaves the second one to the outer list.
+func (p *Parser) consumeClosingAngle() bool {
+	if p.curToken.Kind == SHR {
+		pos := p.curToken.Pos
+		pos.Column++
+		p.curToken = Token{Kind: GT, Value: string(GT), Pos: pos}
+		return true
+	}
+	if !p.expectToken(GT) {
+		return false
+	}
+	p.nextToken() // consume '>'
+	return true
+}
 
-	if !p.expectToken(LBRACE) {
+// parseArrayType parses the length of a fixed-size array type, elem[N]
+func (p *Parser) parseArrayType(elem TypeExpr) TypeExpr {
+	p.nextToken() // consume '['
+	if !p.expectToken(INT) {
 		return nil
 	}
-	p.nextToken() // consume '{'
+	digits, base, suffix := SplitNumber(p.curToken.Value)
+	n, err := strconv.ParseInt(digits, base, 32)
+	if err != nil || n <= 0 || suffix != "" {
+		p.error("array length must be a positive integer, got " + p.curToken.Value)
+		return nil
+	}
+	p.nextToken()
+	if !p.expectToken(RBRACKET) {
+		return nil
+	}
+	p.nextToken() // consume ']'
+	return &ArrayType{Elem: elem, Len: int(n), Pos_: elem.Pos()}
+}
 
-	stmts := []Stmt{}
-	for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
-		stmt := p.parseStmt()
-		if stmt != nil {
+// parseSliceType parses [T*], optionally followed by '?'
+func (p *Parser) parseSliceType() TypeExpr {
+	pos := p.curToken.Pos
+	p.nextToken() // consume '['
+	elem := p.parseType()
+	if elem == nil {
+		return nil
+	}
+	if !p.expectToken(MUL) {
+		return nil
+	}
+	p.nextToken() // consume '*'
+	if !p.expectToken(RBRACKET) {
+		return nil
+	}
+	p.nextToken() // consume ']'
+
+	var result TypeExpr = &SliceType{Elem: elem, Pos_: pos}
+	for p.curToken.Kind == QUESTION {
+		result = &OptionalType{Elem: result, Pos_: pos}
+		p.nextToken()
+	}
+	return result
+}
+
+// parseFuncType parses the type of a function value: fun(i32, i32) : i32.
+// An optional function type is written in parentheses, (fun() : i32)?.
+func (p *Parser) parseFuncType() TypeExpr {
+	typ := &FuncType{Pos_: p.curToken.Pos}
+	p.nextToken() // consume 'fun'
+	if !p.expectToken(LPAREN) {
+		return nil
+	}
+	p.nextToken() // consume '('
+	for p.curToken.Kind != RPAREN && p.curToken.Kind != EOF && !p.hasError {
+		param := p.parseType()
+		if param == nil {
+			return nil
+		}
+		typ.Params = append(typ.Params, param)
+		if p.curToken.Kind == COMMA {
+			p.nextToken()
+		} else if p.curToken.Kind != RPAREN {
+			p.error("expected ',' or ')' in function type")
+			return nil
+		}
+	}
+	if !p.expectToken(RPAREN) {
+		return nil
+	}
+	p.nextToken() // consume ')'
+	if !p.expectToken(COLON) {
+		return nil
+	}
+	p.nextToken() // consume ':'
+	if typ.Result = p.parseType(); typ.Result == nil {
+		return nil
+	}
+	return typ
+}
+
+// parseTupleType parses (T1, T2, ...), optionally followed by '?'. A single
+// parenthesized type is just that type.
+func (p *Parser) parseTupleType() TypeExpr {
+	tuple := &TupleType{Pos_: p.curToken.Pos}
+	p.nextToken() // consume '('
+	for {
+		elem := p.parseType()
+		if elem == nil {
+			return nil
+		}
+		tuple.Elems = append(tuple.Elems, elem)
+		if p.curToken.Kind != COMMA {
+			break
+		}
+		p.nextToken() // consume ','
+	}
+	if !p.expectToken(RPAREN) {
+		return nil
+	}
+	p.nextToken() // consume ')'
+
+	var result TypeExpr = tuple
+	if len(tuple.Elems) == 1 {
+		result = tuple.Elems[0]
+	}
+	for p.curToken.Kind == QUESTION {
+		result = &OptionalType{Elem: result, Pos_: tuple.Pos_}
+		p.nextToken()
+	}
+	return result
+}
+
+func (p *Parser) parseTypeDecl() *TypeDecl {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'type'
+
+	if !p.expectToken(IDENT) {
+		return nil
+	}
+	decl := &TypeDecl{
+		Name: p.curToken.Value,
+		Pos_: pos,
+	}
+	p.nextToken()
+
+	// Optional type parameters: type Box<T> = struct { ... }
+	if p.curToken.Kind == LT {
+		if decl.TypeParams, decl.Bounds = p.parseTypeParams(); decl.TypeParams == nil {
+			return nil
+		}
+	}
+
+	if !p.expectToken(ASSIGN) {
+		return nil
+	}
+	p.nextToken() // consume '='
+
+	switch p.curToken.Kind {
+	case STRUCT:
+		decl.Struct = p.parseStructType()
+	case ENUM:
+		decl.Enum = p.parseEnumType()
+	case INTERFACE:
+		decl.Interface = p.parseInterfaceType()
+	default:
+		p.error("expected struct, enum or interface in type declaration, got " + string(p.curToken.Kind))
+		return nil
+	}
+
+	return decl
+}
+
+func (p *Parser) parseStructType() *StructType {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'struct'
+
+	if !p.expectToken(LBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '{'
+
+	fields := []Field{}
+	for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+		field := Field{Name: p.curToken.Value, Pos_: p.curToken.Pos}
+		p.nextToken()
+
+		if !p.expectToken(COLON) {
+			return nil
+		}
+		p.nextToken() // consume ':'
+
+		if field.Type = p.parseType(); field.Type == nil {
+			return nil
+		}
+		fields = append(fields, field)
+
+		// Fields may optionally be separated by commas
+		if p.curToken.Kind == COMMA {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectToken(RBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '}'
+
+	return &StructType{
+		Fields: fields,
+		Pos_:   pos,
+	}
+}
+
+// parseInterfaceType parses the method signatures of an interface:
+// interface { fun area(self) : f64 }
+func (p *Parser) parseInterfaceType() *InterfaceType {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'interface'
+
+	if !p.expectToken(LBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '{'
+
+	methods := []*FunDecl{}
+	for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
+		if !p.expectToken(FUN) {
+			return nil
+		}
+		method := &FunDecl{Pos_: p.curToken.Pos}
+		p.nextToken() // consume 'fun'
+
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+		method.Name = p.curToken.Value
+		p.nextToken()
+
+		if p.curToken.Kind == LT {
+			p.error("interface method " + method.Name + " cannot have type parameters")
+			return nil
+		}
+		if method.Params, method.Type = p.parseSignature(); method.Type == nil {
+			return nil
+		}
+		methods = append(methods, method)
+	}
+
+	if !p.expectToken(RBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '}'
+
+	return &InterfaceType{
+		Methods: methods,
+		Pos_:    pos,
+	}
+}
+
+func (p *Parser) parseEnumType() *EnumType {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'enum'
+
+	if !p.expectToken(LBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '{'
+
+	variants := []Variant{}
+	for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+		variant := Variant{Name: p.curToken.Value, Pos_: p.curToken.Pos}
+		p.nextToken()
+
+		// Optional payload types
+		if p.curToken.Kind == LPAREN {
+			p.nextToken() // consume '('
+			for p.curToken.Kind != RPAREN && p.curToken.Kind != EOF && !p.hasError {
+				typ := p.parseType()
+				if typ == nil {
+					return nil
+				}
+				variant.Types = append(variant.Types, typ)
+				if p.curToken.Kind == COMMA {
+					p.nextToken()
+				} else if p.curToken.Kind != RPAREN {
+					p.error("expected ',' or ')' in variant payload")
+					return nil
+				}
+			}
+			if !p.expectToken(RPAREN) {
+				return nil
+			}
+			p.nextToken() // consume ')'
+		}
+		variants = append(variants, variant)
+
+		if p.curToken.Kind == COMMA {
+			p.nextToken()
+		} else if p.curToken.Kind != RBRACE {
+			p.error("expected ',' or '}' in enum declaration")
+			return nil
+		}
+	}
+
+	if !p.expectToken(RBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '}'
+
+	return &EnumType{
+		Variants: variants,
+		Pos_:     pos,
+	}
+}
+
+func (p *Parser) parseBlock() *Block {
+	pos := p.curToken.Pos
+
+	if !p.expectToken(LBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '{'
+
+	stmts := []Stmt{}
+	for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
+		stmt := p.parseStmt()
+		if stmt != nil {
 			stmts = append(stmts, stmt)
 		}
 	}
@@ -256,123 +972,448 @@ func (p *Parser) parseBlock() *Block {
 	}
 	p.nextToken() // consume '}'
 
-	return &Block{
-		Stmts: stmts,
-		Pos_:  pos,
-	}
-}
-
-func (p *Parser) parseStmt() Stmt {
-	switch p.curToken.Kind {
-	case DEF:
-		return p.parseVarDecl()
-	case RETURN:
-		return p.parseReturnStmt()
-	case IF:
-		return p.parseIfStmt()
-	case WHILE:
-		return p.parseWhileStmt()
-	case FOR:
-		return p.parseForStmt()
-	default:
-		// Expression statement
-		expr := p.parseExpr()
-		return &ExprStmt{
-			X:    expr,
-			Pos_: expr.Pos(),
-		}
-	}
+	return &Block{
+		Stmts: stmts,
+		Pos_:  pos,
+	}
+}
+
+func (p *Parser) parseStmt() Stmt {
+	switch p.curToken.Kind {
+	case DEF:
+		return p.parseVarDecl()
+	case RETURN:
+		return p.parseReturnStmt()
+	case IF:
+		return p.parseIfStmt()
+	case WHILE:
+		return p.parseWhileStmt()
+	case FOR:
+		return p.parseForStmt()
+	case MATCH:
+		return p.parseMatchStmt()
+	case HANDLE:
+		return p.parseHandleStmt()
+	case BREAK, CONTINUE:
+		return p.parseBranchStmt()
+	case IDENT:
+		if p.peekToken.Kind == COLON {
+			return p.parseLabeledStmt()
+		}
+		return p.parseExprStmt()
+	default:
+		return p.parseExprStmt()
+	}
+}
+
+// parseLabeledStmt parses a loop preceded by a label: outer: while ... { }
+func (p *Parser) parseLabeledStmt() Stmt {
+	label := p.curToken.Value
+	p.nextToken() // consume label
+	p.nextToken() // consume ':'
+
+	switch p.curToken.Kind {
+	case WHILE:
+		stmt := p.parseWhileStmt()
+		stmt.Label = label
+		return stmt
+	case FOR:
+		switch stmt := p.parseForStmt().(type) {
+		case *ForStmt:
+			stmt.Label = label
+			return stmt
+		case *ForInStmt:
+			stmt.Label = label
+			return stmt
+		}
+		return nil
+	default:
+		p.error("label " + label + " must precede a loop")
+		return nil
+	}
+}
+
+// parseBranchStmt parses break or continue. A label must be on the same
+// line, since statements have no terminator.
+func (p *Parser) parseBranchStmt() *BranchStmt {
+	stmt := &BranchStmt{Tok: p.curToken.Kind, Pos_: p.curToken.Pos}
+	p.nextToken() // consume 'break' or 'continue'
+
+	if p.curToken.Kind == IDENT && p.curToken.Pos.Line == stmt.Pos_.Line {
+		stmt.Label = p.curToken.Value
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseExprStmt parses an expression statement, or an assignment when the
+// expression is followed by an assignment operator
+func (p *Parser) parseExprStmt() Stmt {
+	expr := p.parseExpr()
+	if expr == nil {
+		return nil
+	}
+
+	switch p.curToken.Kind {
+	case ASSIGN, PLUS_ASSIGN, MINUS_ASSIGN, MUL_ASSIGN, DIV_ASSIGN, MOD_ASSIGN,
+		AND_ASSIGN, OR_ASSIGN, XOR_ASSIGN, SHL_ASSIGN, SHR_ASSIGN:
+		op := p.curToken.Value
+		p.nextToken() // consume the operator
+		return &AssignStmt{
+			Left:  expr,
+			Op:    op,
+			Right: p.parseExpr(),
+			Pos_:  expr.Pos(),
+		}
+	}
+
+	return &ExprStmt{
+		X:    expr,
+		Pos_: expr.Pos(),
+	}
+}
+
+func (p *Parser) parseReturnStmt() *ReturnStmt {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'return'
+
+	result := p.parseExpr()
+
+	return &ReturnStmt{
+		Result: result,
+		Pos_:   pos,
+	}
+}
+
+func (p *Parser) parseIfStmt() *IfStmt {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'if'
+
+	cond := p.parseControlExpr()
+	body := p.parseBlock()
+
+	var elseStmt Stmt
+	if p.curToken.Kind == ELSE {
+		p.nextToken() // consume 'else'
+		if p.curToken.Kind == IF {
+			elseStmt = p.parseIfStmt()
+		} else {
+			elseStmt = p.parseBlock()
+		}
+	}
+
+	return &IfStmt{
+		Cond: cond,
+		Body: body,
+		Else: elseStmt,
+		Pos_: pos,
+	}
+}
+
+func (p *Parser) parseWhileStmt() *WhileStmt {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'while'
+
+	cond := p.parseControlExpr()
+	body := p.parseBlock()
+
+	return &WhileStmt{
+		Cond: cond,
+		Body: body,
+		Pos_: pos,
+	}
+}
+
+func (p *Parser) parseForStmt() Stmt {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'for'
+
+	// Check for 'for def var in expr' pattern
+	if p.curToken.Kind == DEF {
+		p.nextToken() // consume 'def'
+
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+
+		varName := p.curToken.Value
+		p.nextToken()
+
+		if !p.expectToken(IN) {
+			return nil
+		}
+		p.nextToken() // consume 'in'
+
+		iter := p.parseControlExpr()
+		body := p.parseBlock()
+
+		return &ForInStmt{
+			Var:  varName,
+			Iter: iter,
+			Body: body,
+			Pos_: pos,
+		}
+	}
+
+	if p.curToken.Kind == LPAREN {
+		return p.parseForClauses(pos)
+	}
+
+	p.error("unsupported for loop syntax")
+	return nil
+}
+
+// parseForClauses parses the parenthesized clauses of a C-style for loop and
+// its body: for (init; cond; post) { ... }
+func (p *Parser) parseForClauses(pos diag.Position) Stmt {
+	p.nextToken() // consume '('
+	stmt := &ForStmt{Pos_: pos}
+
+	if p.curToken.Kind != SEMICOLON {
+		if stmt.Init = p.parseSimpleStmt(); stmt.Init == nil {
+			return nil
+		}
+	}
+	if !p.expectToken(SEMICOLON) {
+		return nil
+	}
+	p.nextToken() // consume ';'
+
+	if p.curToken.Kind != SEMICOLON {
+		stmt.Cond = p.parseNestedExpr()
+	}
+	if !p.expectToken(SEMICOLON) {
+		return nil
+	}
+	p.nextToken() // consume ';'
+
+	if p.curToken.Kind != RPAREN {
+		if stmt.Post = p.parseSimpleStmt(); stmt.Post == nil {
+			return nil
+		}
+	}
+	if !p.expectToken(RPAREN) {
+		return nil
+	}
+	p.nextToken() // consume ')'
+
+	stmt.Body = p.parseBlock()
+	return stmt
+}
+
+// parseSimpleStmt parses a variable declaration, an assignment or an
+// expression statement, the statements allowed in for loop clauses
+func (p *Parser) parseSimpleStmt() Stmt {
+	if p.curToken.Kind == DEF {
+		if decl := p.parseVarDecl(); decl != nil {
+			return decl
+		}
+		return nil
+	}
+	saved := p.noStructLit
+	p.noStructLit = false
+	defer func() { p.noStructLit = saved }()
+	return p.parseExprStmt()
+}
+
+func (p *Parser) parseMatchStmt() *MatchStmt {
+	pos := p.curToken.Pos
+	p.nextToken() // consume 'match'
+
+	stmt := &MatchStmt{
+		X:    p.parseControlExpr(),
+		Pos_: pos,
+	}
+
+	if !p.expectToken(LBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '{'
+
+	for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
+		if p.curToken.Kind == ELSE {
+			p.nextToken() // consume 'else'
+			if !p.expectToken(ARROW) {
+				return nil
+			}
+			p.nextToken() // consume '->'
+			stmt.Else = p.parseBlock()
+			continue
+		}
+
+		// Result variants are keywords
+		if p.curToken.Kind != OK && p.curToken.Kind != ERR && !p.expectToken(IDENT) {
+			return nil
+		}
+		arm := MatchArm{Variant: p.curToken.Value, Pos_: p.curToken.Pos}
+		p.nextToken()
+
+		// Optional payload bindings
+		if p.curToken.Kind == LPAREN {
+			p.nextToken() // consume '('
+			for p.curToken.Kind != RPAREN && p.curToken.Kind != EOF && !p.hasError {
+				if !p.expectToken(IDENT) {
+					return nil
+				}
+				arm.Bindings = append(arm.Bindings, p.curToken.Value)
+				p.nextToken()
+				if p.curToken.Kind == COMMA {
+					p.nextToken()
+				} else if p.curToken.Kind != RPAREN {
+					p.error("expected ',' or ')' in match pattern")
+					return nil
+				}
+			}
+			if !p.expectToken(RPAREN) {
+				return nil
+			}
+			p.nextToken() // consume ')'
+		}
+
+		if !p.expectToken(ARROW) {
+			return nil
+		}
+		p.nextToken() // consume '->'
+		arm.Body = p.parseBlock()
+		stmt.Arms = append(stmt.Arms, arm)
+	}
+
+	if !p.expectToken(RBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '}'
+
+	return stmt
 }
 
-func (p *Parser) parseReturnStmt() *ReturnStmt {
+func (p *Parser) parseHandleStmt() *HandleStmt {
 	pos := p.curToken.Pos
-	p.nextToken() // consume 'return'
+	p.nextToken() // consume 'handle'
 
-	result := p.parseExpr()
+	stmt := &HandleStmt{
+		X:    p.parseControlExpr(),
+		Pos_: pos,
+	}
 
-	return &ReturnStmt{
-		Result: result,
-		Pos_:   pos,
+	if !p.expectToken(LBRACE) {
+		return nil
 	}
-}
+	p.nextToken() // consume '{'
 
-func (p *Parser) parseIfStmt() *IfStmt {
-	pos := p.curToken.Pos
-	p.nextToken() // consume 'if'
+	for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
+		kind := p.curToken.Kind
+		if kind != OK && kind != ERR {
+			p.error("expected Ok or Err branch in handle, got " + string(kind))
+			return nil
+		}
+		branch := &HandleBranch{Pos_: p.curToken.Pos}
+		p.nextToken() // consume 'Ok' or 'Err'
 
-	cond := p.parseExpr()
-	body := p.parseBlock()
+		if !p.expectToken(LPAREN) {
+			return nil
+		}
+		p.nextToken() // consume '('
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+		branch.Name = p.curToken.Value
+		p.nextToken()
+		if !p.expectToken(RPAREN) {
+			return nil
+		}
+		p.nextToken() // consume ')'
 
-	var elseStmt Stmt
-	if p.curToken.Kind == ELSE {
-		p.nextToken() // consume 'else'
-		if p.curToken.Kind == IF {
-			elseStmt = p.parseIfStmt()
+		if !p.expectToken(ARROW) {
+			return nil
+		}
+		p.nextToken() // consume '->'
+		branch.Body = p.parseBlock()
+
+		if kind == OK {
+			if stmt.Ok != nil {
+				p.error("duplicate Ok branch in handle")
+				return nil
+			}
+			stmt.Ok = branch
 		} else {
-			elseStmt = p.parseBlock()
+			if stmt.Err != nil {
+				p.error("duplicate Err branch in handle")
+				return nil
+			}
+			stmt.Err = branch
 		}
 	}
 
-	return &IfStmt{
-		Cond: cond,
-		Body: body,
-		Else: elseStmt,
-		Pos_: pos,
+	if !p.expectToken(RBRACE) {
+		return nil
 	}
-}
+	p.nextToken() // consume '}'
 
-func (p *Parser) parseWhileStmt() *WhileStmt {
-	pos := p.curToken.Pos
-	p.nextToken() // consume 'while'
+	return stmt
+}
 
-	cond := p.parseExpr()
-	body := p.parseBlock()
+func (p *Parser) parseExpr() Expr {
+	return p.parseRangeExpr()
+}
 
-	return &WhileStmt{
-		Cond: cond,
-		Body: body,
-		Pos_: pos,
+// parseRangeExpr parses a..b and a..=b, which bind looser than any operator
+func (p *Parser) parseRangeExpr() Expr {
+	left := p.parseOrExpr()
+	if p.curToken.Kind != DOTDOT && p.curToken.Kind != DOTDOTEQ {
+		return left
 	}
+	rng := &RangeExpr{Low: left, Inclusive: p.curToken.Kind == DOTDOTEQ, Pos_: left.Pos()}
+	p.nextToken() // consume '..' or '..='
+	rng.High = p.parseOrExpr()
+	return rng
 }
 
-func (p *Parser) parseForStmt() Stmt {
-	pos := p.curToken.Pos
-	p.nextToken() // consume 'for'
-
-	// Check for 'for def var in expr' pattern
-	if p.curToken.Kind == DEF {
-		p.nextToken() // consume 'def'
-
-		if !p.expectToken(IDENT) {
-			return nil
-		}
-
-		varName := p.curToken.Value
-		p.nextToken()
+// parseIndex parses the index of an index expression, which may be a range
+// with omitted ends: x[i], x[a..b], x[..b], x[a..]
+func (p *Parser) parseIndex() Expr {
+	saved := p.noStructLit
+	p.noStructLit = false
+	defer func() { p.noStructLit = saved }()
 
-		if !p.expectToken(IN) {
+	pos := p.curToken.Pos
+	var low Expr
+	if p.curToken.Kind != DOTDOT && p.curToken.Kind != DOTDOTEQ {
+		if low = p.parseOrExpr(); low == nil {
 			return nil
 		}
-		p.nextToken() // consume 'in'
-
-		iter := p.parseExpr()
-		body := p.parseBlock()
+		pos = low.Pos()
+	}
+	if p.curToken.Kind != DOTDOT && p.curToken.Kind != DOTDOTEQ {
+		return low
+	}
 
-		return &ForInStmt{
-			Var:  varName,
-			Iter: iter,
-			Body: body,
-			Pos_: pos,
-		}
+	rng := &RangeExpr{Low: low, Inclusive: p.curToken.Kind == DOTDOTEQ, Pos_: pos}
+	p.nextToken() // consume '..' or '..='
+	if p.curToken.Kind != RBRACKET {
+		rng.High = p.parseOrExpr()
+	} else if rng.Inclusive {
+		p.error("inclusive range requires an upper bound")
+		return nil
 	}
+	return rng
+}
 
-	// For now, just skip other for loop forms
-	p.error("unsupported for loop syntax")
-	return nil
+// parseControlExpr parses the expression of a control clause such as the
+// condition of an if. A '{' there opens the body, not a struct literal.
+func (p *Parser) parseControlExpr() Expr {
+	saved := p.noStructLit
+	p.noStructLit = true
+	defer func() { p.noStructLit = saved }()
+	return p.parseExpr()
 }
 
-func (p *Parser) parseExpr() Expr {
-	return p.parseOrExpr()
+// parseNestedExpr parses an expression enclosed in delimiters, where struct
+// literals are allowed again
+func (p *Parser) parseNestedExpr() Expr {
+	saved := p.noStructLit
+	p.noStructLit = false
+	defer func() { p.noStructLit = saved }()
+	return p.parseExpr()
 }
 
 func (p *Parser) parseOrExpr() Expr {
@@ -414,10 +1455,90 @@ func (p *Parser) parseAndExpr() Expr {
 }
 
 func (p *Parser) parseCmpExpr() Expr {
-	left := p.parseAddExpr()
+	left := p.parseBitOrExpr()
 
 	for p.curToken.Kind == EQ || p.curToken.Kind == NE || p.curToken.Kind == LT ||
 		p.curToken.Kind == LE || p.curToken.Kind == GT || p.curToken.Kind == GE {
+		op := p.curToken.Value
+		pos := p.curToken.Pos
+		p.nextToken()
+		right := p.parseBitOrExpr()
+		left = &BinaryExpr{
+			Left:  left,
+			Op:    op,
+			Right: right,
+			Pos_:  pos,
+		}
+	}
+
+	return left
+}
+
+// Bitwise operators bind tighter than comparisons, so that x & mask == 0
+// tests the masked bits: | binds loosest, then ^, &, and the shifts, which
+// bind looser than + and -.
+
+func (p *Parser) parseBitOrExpr() Expr {
+	left := p.parseBitXorExpr()
+
+	for p.curToken.Kind == BIT_OR {
+		op := p.curToken.Value
+		pos := p.curToken.Pos
+		p.nextToken()
+		right := p.parseBitXorExpr()
+		left = &BinaryExpr{
+			Left:  left,
+			Op:    op,
+			Right: right,
+			Pos_:  pos,
+		}
+	}
+
+	return left
+}
+
+func (p *Parser) parseBitXorExpr() Expr {
+	left := p.parseBitAndExpr()
+
+	for p.curToken.Kind == BIT_XOR {
+		op := p.curToken.Value
+		pos := p.curToken.Pos
+		p.nextToken()
+		right := p.parseBitAndExpr()
+		left = &BinaryExpr{
+			Left:  left,
+			Op:    op,
+			Right: right,
+			Pos_:  pos,
+		}
+	}
+
+	return left
+}
+
+func (p *Parser) parseBitAndExpr() Expr {
+	left := p.parseShiftExpr()
+
+	for p.curToken.Kind == BIT_AND {
+		op := p.curToken.Value
+		pos := p.curToken.Pos
+		p.nextToken()
+		right := p.parseShiftExpr()
+		left = &BinaryExpr{
+			Left:  left,
+			Op:    op,
+			Right: right,
+			Pos_:  pos,
+		}
+	}
+
+	return left
+}
+
+func (p *Parser) parseShiftExpr() Expr {
+	left := p.parseAddExpr()
+
+	for p.curToken.Kind == SHL || p.curToken.Kind == SHR {
 		op := p.curToken.Value
 		pos := p.curToken.Pos
 		p.nextToken()
@@ -453,13 +1574,13 @@ func (p *Parser) parseAddExpr() Expr {
 }
 
 func (p *Parser) parseMulExpr() Expr {
-	left := p.parseUnaryExpr()
+	left := p.parseCastExpr()
 
 	for p.curToken.Kind == MUL || p.curToken.Kind == DIV || p.curToken.Kind == MOD {
 		op := p.curToken.Value
 		pos := p.curToken.Pos
 		p.nextToken()
-		right := p.parseUnaryExpr()
+		right := p.parseCastExpr()
 		left = &BinaryExpr{
 			Left:  left,
 			Op:    op,
@@ -471,8 +1592,28 @@ func (p *Parser) parseMulExpr() Expr {
 	return left
 }
 
+// parseCastExpr parses conversions, x as T, which bind tighter than binary
+// operators and looser than unary ones: -x as u8 converts -x. The target is
+// a plain type name, so that x as i32 < y compares.
+func (p *Parser) parseCastExpr() Expr {
+	x := p.parseUnaryExpr()
+
+	for p.curToken.Kind == AS && !p.hasError {
+		pos := p.curToken.Pos
+		p.nextToken() // consume 'as'
+		if !p.expectToken(IDENT) {
+			return x
+		}
+		typ := &NamedType{Name: p.curToken.Value, Pos_: p.curToken.Pos}
+		p.nextToken()
+		x = &CastExpr{X: x, Type: typ, Pos_: pos}
+	}
+
+	return x
+}
+
 func (p *Parser) parseUnaryExpr() Expr {
-	if p.curToken.Kind == NOT || p.curToken.Kind == MINUS || p.curToken.Kind == PLUS {
+	if p.curToken.Kind == NOT || p.curToken.Kind == MINUS || p.curToken.Kind == PLUS || p.curToken.Kind == BIT_NOT {
 		op := p.curToken.Value
 		pos := p.curToken.Pos
 		p.nextToken()
@@ -498,7 +1639,7 @@ func (p *Parser) parsePostfixExpr() Expr {
 			args := []Expr{}
 
 			for p.curToken.Kind != RPAREN && p.curToken.Kind != EOF && !p.hasError {
-				arg := p.parseExpr()
+				arg := p.parseNestedExpr()
 				args = append(args, arg)
 
 				if p.curToken.Kind == COMMA {
@@ -519,6 +1660,48 @@ func (p *Parser) parsePostfixExpr() Expr {
 				Args: args,
 				Pos_: left.Pos(),
 			}
+		case DOT:
+			// Qualified reference
+			p.nextToken() // consume '.'
+			if !p.expectToken(IDENT) {
+				return left
+			}
+			left = &SelectorExpr{
+				X:    left,
+				Sel:  p.curToken.Value,
+				Pos_: left.Pos(),
+			}
+			p.nextToken()
+		case LBRACKET:
+			// Index or slice
+			p.nextToken() // consume '['
+			index := p.parseIndex()
+			if index == nil || !p.expectToken(RBRACKET) {
+				return left
+			}
+			p.nextToken() // consume ']'
+			left = &IndexExpr{
+				X:     left,
+				Index: index,
+				Pos_:  left.Pos(),
+			}
+		case QUESTION:
+			// Error propagation
+			left = &TryExpr{
+				X:    left,
+				Pos_: p.curToken.Pos,
+			}
+			p.nextToken() // consume '?'
+		case LBRACE:
+			// Struct literal, only after a (qualified) type name
+			if p.noStructLit || !isTypeExpr(left) {
+				return left
+			}
+			lit := p.parseStructLit(left)
+			if lit == nil {
+				return left
+			}
+			left = lit
 		default:
 			return left
 		}
@@ -527,7 +1710,8 @@ func (p *Parser) parsePostfixExpr() Expr {
 
 func (p *Parser) parsePrimary() Expr {
 	switch p.curToken.Kind {
-	case IDENT:
+	case IDENT, OK, ERR:
+		// Ok and Err construct Results and are otherwise plain names
 		ident := &Ident{
 			Name: p.curToken.Value,
 			Pos_: p.curToken.Pos,
@@ -535,7 +1719,7 @@ func (p *Parser) parsePrimary() Expr {
 		p.nextToken()
 		return ident
 
-	case INT, FLOAT, STRING:
+	case INT, FLOAT, STRING, CHAR:
 		lit := &BasicLit{
 			Kind:  string(p.curToken.Kind),
 			Value: p.curToken.Value,
@@ -544,6 +1728,9 @@ func (p *Parser) parsePrimary() Expr {
 		p.nextToken()
 		return lit
 
+	case STRING_START:
+		return p.parseInterpolatedString()
+
 	case TRUE, FALSE:
 		lit := &BasicLit{
 			Kind:  "BOOL",
@@ -565,15 +1752,42 @@ func (p *Parser) parsePrimary() Expr {
 	case LBRACKET:
 		return p.parseArrayLit()
 
+	case FUN:
+		return p.parseFuncLit()
+
 	case LPAREN:
+		pos := p.curToken.Pos
 		p.nextToken() // consume '('
-		expr := p.parseExpr()
+		expr := p.parseNestedExpr()
+		if p.curToken.Kind == COMMA {
+			tuple := &TupleExpr{Elems: []Expr{expr}, Pos_: pos}
+			for p.curToken.Kind == COMMA && !p.hasError {
+				p.nextToken() // consume ','
+				tuple.Elems = append(tuple.Elems, p.parseNestedExpr())
+			}
+			expr = tuple
+		}
 		if !p.expectToken(RPAREN) {
 			return expr
 		}
 		p.nextToken() // consume ')'
 		return expr
 
+	case ILLEGAL:
+		if isDigit(p.curToken.Value[0]) {
+			p.error("invalid number literal: " + p.curToken.Value)
+		} else if p.curToken.Value[0] == '`' || p.curToken.Value[0] == '"' {
+			p.error("unterminated string literal")
+		} else if p.curToken.Value[0] == '\'' {
+			p.error("invalid character literal: " + p.curToken.Value)
+		} else if p.curToken.Value[0] == '\\' {
+			p.error("invalid escape sequence in string literal: " + p.curToken.Value)
+		} else {
+			p.error("illegal character: " + p.curToken.Value)
+		}
+		p.nextToken() // skip invalid token
+		return &BasicLit{Kind: "INVALID", Value: "", Pos_: p.curToken.Pos}
+
 	default:
 		p.error("unexpected token in expression: " + string(p.curToken.Kind))
 		p.nextToken() // skip invalid token
@@ -581,13 +1795,41 @@ func (p *Parser) parsePrimary() Expr {
 	}
 }
 
+// parseInterpolatedString parses a string literal with embedded expressions,
+// lexed as STRING_START expr { STRING_MID expr } STRING_END
+func (p *Parser) parseInterpolatedString() Expr {
+	str := &InterpolatedString{Pos_: p.curToken.Pos}
+	for !p.hasError {
+		if p.curToken.Value != "" {
+			str.Parts = append(str.Parts, &BasicLit{Kind: "STRING", Value: p.curToken.Value, Pos_: p.curToken.Pos})
+		}
+		if p.curToken.Kind == STRING_END {
+			p.nextToken() // consume the end of the string
+			break
+		}
+		p.nextToken() // consume the text before ${
+		if p.curToken.Kind == STRING_MID || p.curToken.Kind == STRING_END {
+			p.error("empty expression in interpolated string")
+			break
+		}
+		str.Parts = append(str.Parts, p.parseNestedExpr())
+		if p.curToken.Kind == ILLEGAL {
+			// The text after the expression holds an invalid escape
+			p.error("invalid escape sequence in string literal: " + p.curToken.Value)
+		} else if p.curToken.Kind != STRING_MID && p.curToken.Kind != STRING_END {
+			p.error("expected } after interpolated expression, got " + string(p.curToken.Kind))
+		}
+	}
+	return str
+}
+
 func (p *Parser) parseArrayLit() *ArrayLit {
 	pos := p.curToken.Pos
 	p.nextToken() // consume '['
 
 	elements := []Expr{}
 	for p.curToken.Kind != RBRACKET && p.curToken.Kind != EOF && !p.hasError {
-		elem := p.parseExpr()
+		elem := p.parseNestedExpr()
 		elements = append(elements, elem)
 
 		if p.curToken.Kind == COMMA {
@@ -608,3 +1850,55 @@ func (p *Parser) parseArrayLit() *ArrayLit {
 		Pos_:     pos,
 	}
 }
+
+// isTypeExpr reports whether expr can name a type: Name or pkg.Name
+func isTypeExpr(expr Expr) bool {
+	switch e := expr.(type) {
+	case *Ident:
+		return true
+	case *SelectorExpr:
+		_, ok := e.X.(*Ident)
+		return ok
+	default:
+		return false
+	}
+}
+
+func (p *Parser) parseStructLit(typ Expr) *StructLit {
+	p.nextToken() // consume '{'
+
+	fields := []FieldInit{}
+	for p.curToken.Kind != RBRACE && p.curToken.Kind != EOF && !p.hasError {
+		if !p.expectToken(IDENT) {
+			return nil
+		}
+		field := FieldInit{Name: p.curToken.Value, Pos_: p.curToken.Pos}
+		p.nextToken()
+
+		if !p.expectToken(COLON) {
+			return nil
+		}
+		p.nextToken() // consume ':'
+
+		field.Value = p.parseNestedExpr()
+		fields = append(fields, field)
+
+		if p.curToken.Kind == COMMA {
+			p.nextToken()
+		} else if p.curToken.Kind != RBRACE {
+			p.error("expected ',' or '}' in struct literal")
+			break
+		}
+	}
+
+	if !p.expectToken(RBRACE) {
+		return nil
+	}
+	p.nextToken() // consume '}'
+
+	return &StructLit{
+		Type:   typ,
+		Fields: fields,
+		Pos_:   typ.Pos(),
+	}
+}
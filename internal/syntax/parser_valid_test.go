@@ -31,6 +31,45 @@ func TestParser_ParseSimple(t *testing.T) {
 	}
 }
 
+func TestParser_ParseControlFlow(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "control-flow.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	// else if chains an IfStmt as the else branch
+	classify := file.Decls[1].(*FunDecl)
+	ifStmt := classify.Body.Stmts[0].(*IfStmt)
+	if _, ok := ifStmt.Else.(*IfStmt); !ok {
+		t.Fatalf("expected else if, got %#v", ifStmt.Else)
+	}
+
+	depth := file.Decls[2].(*FunDecl)
+	if _, ok := depth.Body.Stmts[0].(*WhileStmt); !ok {
+		t.Fatalf("expected while loop, got %#v", depth.Body.Stmts[0])
+	}
+
+	// && binds tighter than ||
+	main := file.Decls[3].(*FunDecl)
+	ok := main.Body.Stmts[0].(*VarDecl)
+	or, isBinary := ok.Init.(*BinaryExpr)
+	if !isBinary || or.Op != "||" {
+		t.Fatalf("expected || at the root, got %#v", ok.Init)
+	}
+	and, isBinary := or.Left.(*BinaryExpr)
+	if !isBinary || and.Op != "&&" {
+		t.Fatalf("expected && operand, got %#v", or.Left)
+	}
+	if not, isUnary := and.Left.(*UnaryExpr); !isUnary || not.Op != "!" {
+		t.Fatalf("expected ! operand, got %#v", and.Left)
+	}
+}
+
 func TestParser_ParseHello(t *testing.T) {
 	path := repoPathSyntax(filepath.Join("test-data", "hello.gvt"))
 	src, err := os.ReadFile(path)
@@ -55,3 +94,921 @@ func TestParser_ParseHello(t *testing.T) {
 		t.Fatalf("expected at least 2 functions, got %d", funCount)
 	}
 }
+
+func TestParser_ParseImports(t *testing.T) {
+	src := `package app.cli
+
+import util.strings
+import util.math { max, min }
+import util.io as io
+import { net.http { get } as web, os }
+
+fun main() : none {
+    print(io.name)
+}`
+	file, diags := ParseFile("<mem>", src)
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+	if want := "app.cli"; file.Package != want {
+		t.Fatalf("expected package %q, got %q", want, file.Package)
+	}
+	if len(file.Imports) != 5 {
+		t.Fatalf("expected 5 import clauses, got %d", len(file.Imports))
+	}
+
+	want := []struct {
+		path, name string
+		symbols    int
+	}{
+		{"util.strings", "strings", 0},
+		{"util.math", "math", 2},
+		{"util.io", "io", 0},
+		{"net.http", "web", 1},
+		{"os", "os", 0},
+	}
+	for i, w := range want {
+		imp := file.Imports[i]
+		if imp.Path != w.path || imp.Name() != w.name || len(imp.Symbols) != w.symbols {
+			t.Fatalf("import %d: expected %s as %s with %d symbols, got %#v", i, w.path, w.name, w.symbols, imp)
+		}
+	}
+}
+
+func TestParser_ParseExports(t *testing.T) {
+	src := `package util.geo
+
+export type Point = struct {
+    x: i32
+}
+
+type Secret = struct {
+    v: i32
+}
+
+export def origin: i32 = 0
+def limit: i32 = 10
+
+export fun make(x: i32) : Point {
+    return Point{x: x}
+}
+
+fun helper() : i32 {
+    return limit
+}`
+	file, diags := ParseFile("<mem>", src)
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+	if len(file.Decls) != 6 {
+		t.Fatalf("expected 6 declarations, got %d", len(file.Decls))
+	}
+	want := []bool{true, false, true, false, true, false}
+	for i, d := range file.Decls {
+		var got bool
+		switch d := d.(type) {
+		case *TypeDecl:
+			got = d.Exported
+		case *VarDecl:
+			got = d.Exported
+		case *FunDecl:
+			got = d.Exported
+		default:
+			t.Fatalf("decl %d: unexpected %T", i, d)
+		}
+		if got != want[i] {
+			t.Fatalf("decl %d: expected exported=%v, got %v", i, want[i], got)
+		}
+	}
+
+	_, diags = ParseFile("<mem>", "package p\n\nexport impl Point {\n}\n")
+	if len(diags) == 0 || diags[0].Message != "expected def, fun or type after export, got IMPL" {
+		t.Fatalf("expected export error, got %#v", diags)
+	}
+}
+
+func TestParser_ParseStructs(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "structs.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	typeDecl, ok := file.Decls[0].(*TypeDecl)
+	if !ok || typeDecl.Name != "Point" || typeDecl.Struct == nil || len(typeDecl.Struct.Fields) != 2 {
+		t.Fatalf("expected struct Point with 2 fields, got %#v", file.Decls[0])
+	}
+
+	// def q = add(p, Point { x: 3, y: 4 })
+	main := file.Decls[2].(*FunDecl)
+	call := main.Body.Stmts[1].(*VarDecl).Init.(*CallExpr)
+	lit, ok := call.Args[1].(*StructLit)
+	if !ok || len(lit.Fields) != 2 {
+		t.Fatalf("expected struct literal argument, got %#v", call.Args[1])
+	}
+
+	// if q.y == 6 { ... } must not parse `6 {` or `q.y {` as a struct literal
+	ifStmt := main.Body.Stmts[2].(*IfStmt)
+	cond := ifStmt.Cond.(*BinaryExpr)
+	if sel, ok := cond.Left.(*SelectorExpr); !ok || sel.Sel != "y" {
+		t.Fatalf("expected field access q.y in condition, got %#v", cond.Left)
+	}
+	if ifStmt.Body == nil || len(ifStmt.Body.Stmts) != 1 {
+		t.Fatalf("expected if body with 1 statement")
+	}
+}
+
+func TestParser_ParseEnums(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "enums.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	typeDecl, ok := file.Decls[0].(*TypeDecl)
+	if !ok || typeDecl.Enum == nil || len(typeDecl.Enum.Variants) != 3 {
+		t.Fatalf("expected enum Shape with 3 variants, got %#v", file.Decls[0])
+	}
+	if rect := typeDecl.Enum.Variants[1]; rect.Name != "Rect" || len(rect.Types) != 2 {
+		t.Fatalf("expected variant Rect(f64, f64), got %#v", rect)
+	}
+	if empty := typeDecl.Enum.Variants[2]; empty.Name != "Empty" || len(empty.Types) != 0 {
+		t.Fatalf("expected plain variant Empty, got %#v", empty)
+	}
+
+	area := file.Decls[1].(*FunDecl)
+	match, ok := area.Body.Stmts[0].(*MatchStmt)
+	if !ok || len(match.Arms) != 3 || match.Else != nil {
+		t.Fatalf("expected match with 3 arms, got %#v", area.Body.Stmts[0])
+	}
+	if arm := match.Arms[1]; arm.Variant != "Rect" || len(arm.Bindings) != 2 || arm.Bindings[1] != "h" {
+		t.Fatalf("expected arm Rect(w, h), got %#v", arm)
+	}
+
+	// match Shape.Circle(1.0) { ... else -> { ... } }
+	main := file.Decls[2].(*FunDecl)
+	match, ok = main.Body.Stmts[2].(*MatchStmt)
+	if !ok || len(match.Arms) != 1 || match.Else == nil {
+		t.Fatalf("expected match with else arm, got %#v", main.Body.Stmts[2])
+	}
+}
+
+func TestParser_ParseImplBlocks(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "methods.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	impl, ok := file.Decls[2].(*ImplDecl)
+	if !ok || impl.Type != "Vec" || len(impl.Methods) != 3 {
+		t.Fatalf("expected impl Vec with 3 methods, got %#v", file.Decls[2])
+	}
+	if impl.Methods[0].HasReceiver() {
+		t.Fatalf("expected origin to have no receiver")
+	}
+	add := impl.Methods[2]
+	if !add.HasReceiver() || len(add.Params) != 2 || add.Params[1].Type.String() != "Vec" {
+		t.Fatalf("expected add(self, other: Vec), got %#v", add.Params)
+	}
+
+	// def v = Vec.origin().add(...) chains a method call on a call result
+	main := file.Decls[4].(*FunDecl)
+	call := main.Body.Stmts[0].(*VarDecl).Init.(*CallExpr)
+	sel, ok := call.Fun.(*SelectorExpr)
+	if !ok || sel.Sel != "add" {
+		t.Fatalf("expected method call add, got %#v", call.Fun)
+	}
+	if _, ok := sel.X.(*CallExpr); !ok {
+		t.Fatalf("expected receiver Vec.origin(), got %#v", sel.X)
+	}
+}
+
+func TestParser_ParseGenericFunctions(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "generics.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	max := file.Decls[1].(*FunDecl)
+	if len(max.TypeParams) != 1 || max.TypeParams[0] != "T" || max.Params[1].Type.String() != "T" {
+		t.Fatalf("expected max<T>(a: T, b: T), got %#v", max)
+	}
+	first := file.Decls[2].(*FunDecl)
+	if len(first.TypeParams) != 2 || first.TypeParams[1] != "B" || first.Type.String() != "A" {
+		t.Fatalf("expected first<A, B>(...) : A, got %#v", first)
+	}
+
+	// A comparison in a body must still parse as a binary expression
+	ifStmt := max.Body.Stmts[0].(*IfStmt)
+	if cond, ok := ifStmt.Cond.(*BinaryExpr); !ok || cond.Op != ">" {
+		t.Fatalf("expected a > b condition, got %#v", ifStmt.Cond)
+	}
+}
+
+func TestParser_ParseGenericTypes(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "generic-types.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	pair := file.Decls[1].(*TypeDecl)
+	if pair.Name != "Pair" || len(pair.TypeParams) != 2 || pair.Struct == nil {
+		t.Fatalf("expected struct Pair<A, B>, got %#v", pair)
+	}
+	impl := file.Decls[3].(*ImplDecl)
+	if len(impl.TypeParams) != 1 || impl.Type != "Box" {
+		t.Fatalf("expected impl<T> Box, got %#v", impl)
+	}
+
+	// fun swap<A, B>(p: Pair<A, B>) : Pair<B, A>
+	swap := file.Decls[4].(*FunDecl)
+	result, ok := swap.Type.(*NamedType)
+	if !ok || result.Name != "Pair" || len(result.Args) != 2 || result.Args[0].String() != "B" {
+		t.Fatalf("expected result type Pair<B, A>, got %#v", swap.Type)
+	}
+	if got := swap.Params[0].Type.String(); got != "Pair<A, B>" {
+		t.Fatalf("expected parameter type Pair<A, B>, got %s", got)
+	}
+
+	// def name: Box<string> = ...
+	main := file.Decls[6].(*FunDecl)
+	if got := main.Body.Stmts[1].(*VarDecl).Type.String(); got != "Box<string>" {
+		t.Fatalf("expected declared type Box<string>, got %s", got)
+	}
+}
+
+func TestParser_ParseNestedTypeArguments(t *testing.T) {
+	src := "package main\n\ndef b: util.Box<Pair<i32, Box<f64>>> = x\n"
+	file, diags := ParseFile("nested.gvt", src)
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+	typ := file.Decls[0].(*VarDecl).Type.(*NamedType)
+	if typ.Pkg != "util" || typ.Name != "Box" {
+		t.Fatalf("expected util.Box, got %#v", typ)
+	}
+	if got := typ.String(); got != "util.Box<Pair<i32, Box<f64>>>" {
+		t.Fatalf("unexpected type %s", got)
+	}
+}
+
+func TestParser_ParseHandle(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "results.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	// return Err("division by zero")
+	divide := file.Decls[0].(*FunDecl)
+	ret := divide.Body.Stmts[0].(*IfStmt).Body.Stmts[0].(*ReturnStmt)
+	call, ok := ret.Result.(*CallExpr)
+	if !ok || call.Fun.(*Ident).Name != "Err" {
+		t.Fatalf("expected Err(...) call, got %#v", ret.Result)
+	}
+
+	main := file.Decls[1].(*FunDecl)
+	handle, ok := main.Body.Stmts[0].(*HandleStmt)
+	if !ok || handle.Ok == nil || handle.Err == nil {
+		t.Fatalf("expected handle with both branches, got %#v", main.Body.Stmts[0])
+	}
+	if handle.Ok.Name != "q" || handle.Err.Name != "e" {
+		t.Fatalf("expected bindings q and e, got %s and %s", handle.Ok.Name, handle.Err.Name)
+	}
+
+	// Branches may come in any order
+	handle = main.Body.Stmts[1].(*HandleStmt)
+	if handle.Ok == nil || handle.Err == nil || len(handle.Err.Body.Stmts) != 1 {
+		t.Fatalf("expected handle with Err branch first, got %#v", handle)
+	}
+}
+
+func TestParser_ParseTry(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "try.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	chain := file.Decls[1].(*FunDecl)
+	decl := chain.Body.Stmts[0].(*VarDecl)
+	try, ok := decl.Init.(*TryExpr)
+	if !ok {
+		t.Fatalf("expected try expression, got %#v", decl.Init)
+	}
+	if _, ok := try.X.(*CallExpr); !ok {
+		t.Fatalf("expected ? applied to a call, got %#v", try.X)
+	}
+
+	// ? binds tighter than binary operators: Ok(divide(q, 2)? + 1)
+	ret := chain.Body.Stmts[1].(*ReturnStmt)
+	sum, ok := ret.Result.(*CallExpr).Args[0].(*BinaryExpr)
+	if !ok || sum.Op != "+" {
+		t.Fatalf("expected sum, got %#v", ret.Result.(*CallExpr).Args[0])
+	}
+	if _, ok := sum.Left.(*TryExpr); !ok {
+		t.Fatalf("expected try expression as left operand, got %#v", sum.Left)
+	}
+}
+
+func TestParser_ParseOptionals(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "optionals.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	find := file.Decls[1].(*FunDecl)
+	opt, ok := find.Type.(*OptionalType)
+	if !ok || opt.Elem.String() != "i32" {
+		t.Fatalf("expected result type i32?, got %#v", find.Type)
+	}
+
+	// return none
+	ret := find.Body.Stmts[0].(*IfStmt).Body.Stmts[0].(*ReturnStmt)
+	if lit, ok := ret.Result.(*BasicLit); !ok || lit.Kind != "NONE" {
+		t.Fatalf("expected none literal, got %#v", ret.Result)
+	}
+
+	origin := file.Decls[2].(*FunDecl)
+	if got := origin.Type.String(); got != "Point?" {
+		t.Fatalf("expected result type Point?, got %s", got)
+	}
+
+	main := file.Decls[4].(*FunDecl)
+	decl := main.Body.Stmts[3].(*VarDecl)
+	if decl.Name != "c" || decl.Type.String() != "i32?" {
+		t.Fatalf("expected c: i32?, got %s: %s", decl.Name, printType(decl.Type))
+	}
+}
+
+func TestParser_ParseForLoops(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "for.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	main := file.Decls[1].(*FunDecl)
+	loop, ok := main.Body.Stmts[0].(*ForStmt)
+	if !ok {
+		t.Fatalf("expected for loop, got %#v", main.Body.Stmts[0])
+	}
+	if init, ok := loop.Init.(*VarDecl); !ok || init.Name != "i" {
+		t.Fatalf("expected init def i, got %#v", loop.Init)
+	}
+	if _, ok := loop.Cond.(*CallExpr); !ok {
+		t.Fatalf("expected call as condition, got %#v", loop.Cond)
+	}
+	if _, ok := loop.Post.(*ExprStmt); !ok {
+		t.Fatalf("expected expression as post statement, got %#v", loop.Post)
+	}
+
+	// for (;;) leaves every clause empty
+	forever := main.Body.Stmts[1].(*ForStmt)
+	if forever.Init != nil || forever.Cond != nil || forever.Post != nil {
+		t.Fatalf("expected empty clauses, got %#v", forever)
+	}
+	if len(forever.Body.Stmts) != 1 {
+		t.Fatalf("expected 1 statement in body, got %d", len(forever.Body.Stmts))
+	}
+}
+
+func TestParser_ParseAssignments(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "assign.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	// self.x += 10
+	bump := file.Decls[1].(*ImplDecl).Methods[0]
+	assign, ok := bump.Body.Stmts[0].(*AssignStmt)
+	if !ok || assign.Op != "+=" {
+		t.Fatalf("expected compound assignment, got %#v", bump.Body.Stmts[0])
+	}
+	if _, ok := assign.Left.(*SelectorExpr); !ok {
+		t.Fatalf("expected field target, got %#v", assign.Left)
+	}
+
+	// for (def i = 0; i < 5; i += 1)
+	main := file.Decls[4].(*FunDecl)
+	loop := main.Body.Stmts[1].(*ForStmt)
+	if post, ok := loop.Post.(*AssignStmt); !ok || post.Op != "+=" {
+		t.Fatalf("expected i += 1 as post statement, got %#v", loop.Post)
+	}
+
+	// x = x + 1
+	while := main.Body.Stmts[3].(*WhileStmt)
+	assign, ok = while.Body.Stmts[0].(*AssignStmt)
+	if !ok || assign.Op != "=" {
+		t.Fatalf("expected assignment, got %#v", while.Body.Stmts[0])
+	}
+	if _, ok := assign.Right.(*BinaryExpr); !ok {
+		t.Fatalf("expected binary expression as value, got %#v", assign.Right)
+	}
+}
+
+func TestParser_ParseBranches(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "loops.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	main := file.Decls[0].(*FunDecl)
+	outer, ok := main.Body.Stmts[1].(*ForStmt)
+	if !ok || outer.Label != "outer" {
+		t.Fatalf("expected for loop labeled outer, got %#v", main.Body.Stmts[1])
+	}
+
+	inner := outer.Body.Stmts[1].(*WhileStmt)
+	if inner.Label != "" {
+		t.Fatalf("expected unlabeled while loop, got label %q", inner.Label)
+	}
+	want := []struct {
+		tok   TokenKind
+		label string
+	}{{CONTINUE, "outer"}, {BREAK, "outer"}, {BREAK, ""}}
+	for i, w := range want {
+		branch, ok := inner.Body.Stmts[i+1].(*IfStmt).Body.Stmts[0].(*BranchStmt)
+		if !ok || branch.Tok != w.tok || branch.Label != w.label {
+			t.Fatalf("branch %d: expected %s %q, got %#v", i, w.tok, w.label, inner.Body.Stmts[i+1])
+		}
+	}
+}
+
+func TestParser_ParseRanges(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "ranges.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	main := file.Decls[0].(*FunDecl)
+	loop, ok := main.Body.Stmts[1].(*ForInStmt)
+	if !ok {
+		t.Fatalf("expected for-in loop, got %#v", main.Body.Stmts[1])
+	}
+	rng, ok := loop.Iter.(*RangeExpr)
+	if !ok || rng.Inclusive || rng.Low == nil || rng.High == nil {
+		t.Fatalf("expected half-open range, got %#v", loop.Iter)
+	}
+
+	inclusive := main.Body.Stmts[4].(*ForInStmt).Iter.(*RangeExpr)
+	if !inclusive.Inclusive {
+		t.Fatalf("expected inclusive range, got %#v", inclusive)
+	}
+
+	// print(s[0..5]), print(s[7..]), print(s[..=3]), print(s[..])
+	want := []struct {
+		low, high, inclusive bool
+	}{{true, true, false}, {true, false, false}, {false, true, true}, {false, false, false}}
+	for i, w := range want {
+		call := main.Body.Stmts[11+i].(*ExprStmt).X.(*CallExpr)
+		index, ok := call.Args[0].(*IndexExpr)
+		if !ok {
+			t.Fatalf("slice %d: expected index expression, got %#v", i, call.Args[0])
+		}
+		bounds := index.Index.(*RangeExpr)
+		if (bounds.Low != nil) != w.low || (bounds.High != nil) != w.high || bounds.Inclusive != w.inclusive {
+			t.Fatalf("slice %d: unexpected bounds %#v", i, bounds)
+		}
+	}
+}
+
+func TestParser_ParseTuples(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "tuples.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	divmod := file.Decls[0].(*FunDecl)
+	result, ok := divmod.Type.(*TupleType)
+	if !ok || len(result.Elems) != 2 {
+		t.Fatalf("expected (i32, i32) result, got %#v", divmod.Type)
+	}
+	ret := divmod.Body.Stmts[0].(*ReturnStmt)
+	if tuple, ok := ret.Result.(*TupleExpr); !ok || len(tuple.Elems) != 2 {
+		t.Fatalf("expected tuple expression, got %#v", ret.Result)
+	}
+
+	main := file.Decls[3].(*FunDecl)
+	decl := main.Body.Stmts[0].(*VarDecl)
+	if decl.Name != "" || len(decl.Names) != 2 || decl.Names[0] != "q" || decl.Names[1] != "r" {
+		t.Fatalf("expected def (q, r), got %#v", decl)
+	}
+	if blank := main.Body.Stmts[1].(*VarDecl); blank.Names[0] != "_" {
+		t.Fatalf("expected blank first name, got %v", blank.Names)
+	}
+}
+
+func TestParser_ParseArrays(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "arrays.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	primes := file.Decls[0].(*VarDecl)
+	if lit, ok := primes.Init.(*ArrayLit); !ok || len(lit.Elements) != 4 {
+		t.Fatalf("expected array literal with 4 elements, got %#v", primes.Init)
+	}
+
+	sum := file.Decls[1].(*FunDecl)
+	array, ok := sum.Params[0].Type.(*ArrayType)
+	if !ok || array.Len != 4 || array.String() != "i32[4]" {
+		t.Fatalf("expected i32[4] parameter, got %#v", sum.Params[0].Type)
+	}
+
+	main := file.Decls[2].(*FunDecl)
+	assign := main.Body.Stmts[1].(*AssignStmt)
+	index, ok := assign.Left.(*IndexExpr)
+	if !ok {
+		t.Fatalf("expected element assignment, got %#v", assign.Left)
+	}
+	if lit, ok := index.Index.(*BasicLit); !ok || lit.Value != "0" {
+		t.Fatalf("expected index 0, got %#v", index.Index)
+	}
+}
+
+func TestParser_ParseSlices(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "slices.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	total := file.Decls[1].(*FunDecl)
+	slice, ok := total.Params[0].Type.(*SliceType)
+	if !ok || slice.String() != "[Record*]" {
+		t.Fatalf("expected [Record*] parameter, got %#v", total.Params[0].Type)
+	}
+
+	main := file.Decls[3].(*FunDecl)
+	records := main.Body.Stmts[0].(*VarDecl)
+	if lit, ok := records.Init.(*ArrayLit); !ok || len(lit.Elements) != 0 {
+		t.Fatalf("expected empty literal, got %#v", records.Init)
+	}
+	loop := main.Body.Stmts[1].(*ForInStmt)
+	assign := loop.Body.Stmts[0].(*AssignStmt)
+	call, ok := assign.Right.(*CallExpr)
+	if !ok || call.Fun.(*Ident).Name != "append" || len(call.Args) != 2 {
+		t.Fatalf("expected append call, got %#v", assign.Right)
+	}
+}
+
+func TestParser_ParseClosures(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "closures.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	apply := file.Decls[2].(*FunDecl)
+	fnType, ok := apply.Params[0].Type.(*FuncType)
+	if !ok || len(fnType.Params) != 1 || fnType.String() != "fun(i32) : i32" {
+		t.Fatalf("expected fun(i32) : i32 parameter, got %#v", apply.Params[0].Type)
+	}
+
+	makeAdder := file.Decls[3].(*FunDecl)
+	if _, ok := makeAdder.Type.(*FuncType); !ok {
+		t.Fatalf("expected function result type, got %#v", makeAdder.Type)
+	}
+	ret := makeAdder.Body.Stmts[0].(*ReturnStmt)
+	lit, ok := ret.Result.(*FuncLit)
+	if !ok || len(lit.Params) != 1 || lit.Params[0].Name != "x" || len(lit.Body.Stmts) != 1 {
+		t.Fatalf("expected function literal, got %#v", ret.Result)
+	}
+
+	// makeAdder(1)(1) calls the result of a call
+	main := file.Decls[5].(*FunDecl)
+	total := main.Body.Stmts[7].(*VarDecl)
+	sum := total.Init.(*BinaryExpr)
+	call, ok := sum.Right.(*CallExpr)
+	if !ok {
+		t.Fatalf("expected call, got %#v", sum.Right)
+	}
+	if _, ok := call.Fun.(*CallExpr); !ok {
+		t.Fatalf("expected call of a call, got %#v", call.Fun)
+	}
+}
+
+func TestParser_ParseBitwise(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "bitwise.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	main := file.Decls[2].(*FunDecl)
+	nested := main.Body.Stmts[0].(*VarDecl)
+	if nested.Type.String() != "Box<Box<i32>>" {
+		t.Fatalf("expected Box<Box<i32>>, got %s", nested.Type)
+	}
+
+	shifted := main.Body.Stmts[2].(*AssignStmt)
+	if shifted.Op != "|=" {
+		t.Fatalf("expected |=, got %s", shifted.Op)
+	}
+	if shl, ok := shifted.Right.(*BinaryExpr); !ok || shl.Op != "<<" {
+		t.Fatalf("expected shift, got %#v", shifted.Right)
+	}
+
+	// 6 & 3 == 2 compares the masked value
+	cond := main.Body.Stmts[10].(*IfStmt).Cond
+	for {
+		and, ok := cond.(*BinaryExpr)
+		if !ok || and.Op != "&&" {
+			break
+		}
+		cond = and.Left
+	}
+	cmp, ok := cond.(*BinaryExpr)
+	if !ok || cmp.Op != "==" {
+		t.Fatalf("expected comparison, got %#v", cond)
+	}
+	if mask, ok := cmp.Left.(*BinaryExpr); !ok || mask.Op != "&" {
+		t.Fatalf("expected & below ==, got %#v", cmp.Left)
+	}
+}
+
+func TestParser_ParseNumbers(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "numbers.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	mask := file.Decls[0].(*VarDecl)
+	if lit, ok := mask.Init.(*BasicLit); !ok || lit.Kind != "INT" || lit.Value != "0xFFFF_0000" {
+		t.Fatalf("expected hex literal, got %#v", mask.Init)
+	}
+
+	main := file.Decls[2].(*FunDecl)
+	kinds := []string{"INT", "INT", "INT", "", "FLOAT", "FLOAT", "INT"}
+	for i, kind := range kinds {
+		decl := main.Body.Stmts[i].(*VarDecl)
+		if lit, ok := decl.Init.(*BasicLit); kind != "" && (!ok || lit.Kind != kind) {
+			t.Fatalf("statement %d: expected %s literal, got %#v", i, kind, decl.Init)
+		}
+	}
+}
+
+func TestParser_ParseInterpolation(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "interpolation.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	describe := file.Decls[1].(*FunDecl)
+	str, ok := describe.Body.Stmts[0].(*ReturnStmt).Result.(*InterpolatedString)
+	if !ok || len(str.Parts) != 5 {
+		t.Fatalf("expected 5 parts, got %#v", describe.Body.Stmts[0].(*ReturnStmt).Result)
+	}
+	if lit, ok := str.Parts[0].(*BasicLit); !ok || lit.Value != "(" {
+		t.Fatalf("expected text part, got %#v", str.Parts[0])
+	}
+	if sel, ok := str.Parts[1].(*SelectorExpr); !ok || sel.Sel != "x" {
+		t.Fatalf("expected p.x, got %#v", str.Parts[1])
+	}
+
+	main := file.Decls[2].(*FunDecl)
+	sum := main.Body.Stmts[3].(*ExprStmt).X.(*CallExpr)
+	str, ok = sum.Args[0].(*InterpolatedString)
+	if !ok || len(str.Parts) != 2 {
+		t.Fatalf("expected interpolated argument, got %#v", sum.Args[0])
+	}
+	if bin, ok := str.Parts[1].(*BinaryExpr); !ok || bin.Op != "+" {
+		t.Fatalf("expected a + b, got %#v", str.Parts[1])
+	}
+}
+
+func TestParser_ParseRawStrings(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "raw-strings.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	pattern := file.Decls[0].(*VarDecl).Init.(*BasicLit)
+	if pattern.Value != `^\d+(\.\d+)?$` {
+		t.Fatalf("expected verbatim pattern, got %q", pattern.Value)
+	}
+
+	query := file.Decls[1].(*FunDecl)
+	sql := query.Body.Stmts[0].(*VarDecl).Init.(*BasicLit)
+	if sql.Value != "SELECT id, name\n  FROM ${table}\n WHERE id > 10" {
+		t.Fatalf("expected dedented query, got %q", sql.Value)
+	}
+	if ret := query.Body.Stmts[1].(*ReturnStmt); ret.Pos().Line != 11 {
+		t.Fatalf("expected return on line 11, got %d", ret.Pos().Line)
+	}
+}
+
+func TestParser_ParseChars(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "chars.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	isDigit := file.Decls[0].(*FunDecl)
+	cond := isDigit.Body.Stmts[0].(*ReturnStmt).Result.(*BinaryExpr)
+	low, ok := cond.Left.(*BinaryExpr).Right.(*BasicLit)
+	if !ok || low.Kind != "CHAR" || low.Value != "0" {
+		t.Fatalf("expected '0', got %#v", cond.Left.(*BinaryExpr).Right)
+	}
+
+	main := file.Decls[3].(*FunDecl)
+	invader := main.Body.Stmts[0].(*VarDecl)
+	if invader.Type.String() != "rune" {
+		t.Fatalf("expected rune, got %s", invader.Type)
+	}
+	if lit := invader.Init.(*BasicLit); lit.Kind != "CHAR" || lit.Value != "👾" {
+		t.Fatalf("expected invader literal, got %#v", lit)
+	}
+}
+
+func TestParser_ParseConversions(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "conversions.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	// sum as f64 / len(xs) as f64 divides two conversions
+	average := file.Decls[0].(*FunDecl)
+	div := average.Body.Stmts[2].(*ReturnStmt).Result.(*BinaryExpr)
+	if div.Op != "/" {
+		t.Fatalf("expected /, got %s", div.Op)
+	}
+	for _, side := range []Expr{div.Left, div.Right} {
+		if cast, ok := side.(*CastExpr); !ok || cast.Type.String() != "f64" {
+			t.Fatalf("expected conversion to f64, got %#v", side)
+		}
+	}
+
+	// -2 as i64 as u64 converts twice, after negating
+	main := file.Decls[1].(*FunDecl)
+	cond := main.Body.Stmts[5].(*IfStmt).Cond.(*BinaryExpr).Right.(*BinaryExpr)
+	outer, ok := cond.Left.(*CastExpr)
+	if !ok || outer.Type.String() != "u64" {
+		t.Fatalf("expected conversion to u64, got %#v", cond.Left)
+	}
+	inner, ok := outer.X.(*CastExpr)
+	if !ok || inner.Type.String() != "i64" {
+		t.Fatalf("expected conversion to i64, got %#v", outer.X)
+	}
+	if neg, ok := inner.X.(*UnaryExpr); !ok || neg.Op != "-" {
+		t.Fatalf("expected negation, got %#v", inner.X)
+	}
+}
+
+func TestParser_ParseInterfaces(t *testing.T) {
+	path := repoPathSyntax(filepath.Join("test-data", "interfaces.gvt"))
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fixture missing: %v", err)
+	}
+	file, diags := ParseFile(path, string(src))
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+
+	shape := file.Decls[0].(*TypeDecl)
+	if shape.Interface == nil || len(shape.Interface.Methods) != 3 {
+		t.Fatalf("expected interface Shape with 3 methods, got %#v", shape)
+	}
+	scale := shape.Interface.Methods[2]
+	if !scale.HasReceiver() || len(scale.Params) != 2 || scale.Body != nil {
+		t.Fatalf("expected signature scale(self, k: i32) without body, got %#v", scale)
+	}
+
+	// type Framed<T: Shape> = struct { ... }
+	framed := file.Decls[3].(*TypeDecl)
+	if len(framed.Bounds) != 1 || framed.Bounds[0].String() != "Shape" {
+		t.Fatalf("expected bound T: Shape, got %#v", framed.Bounds)
+	}
+
+	impl := file.Decls[4].(*ImplDecl)
+	if impl.Interface == nil || impl.Interface.String() != "Shape" || impl.Type != "Rect" || len(impl.Methods) != 3 {
+		t.Fatalf("expected impl Shape for Rect, got %#v", impl)
+	}
+	generic := file.Decls[6].(*ImplDecl)
+	if len(generic.TypeParams) != 1 || generic.Interface == nil || generic.Type != "Framed" {
+		t.Fatalf("expected impl<T> Shape for Framed, got %#v", generic)
+	}
+
+	double := file.Decls[7].(*FunDecl)
+	if len(double.TypeParams) != 1 || len(double.Bounds) != 1 || double.Bounds[0].String() != "Shape" {
+		t.Fatalf("expected double_area<T: Shape>, got %#v", double)
+	}
+
+	// Only some parameters may be bounded, and interfaces may be imported
+	mem := "package main\n\nfun f<A, B: geo.Shape>(a: A, b: B) : i32 {\n    return 0\n}\n\nimpl geo.Shape for Rect {\n}\n"
+	file, diags = ParseFile("<mem>", mem)
+	if len(diags) != 0 {
+		t.Fatalf("expected no diagnostics, got %d: %#v", len(diags), diags)
+	}
+	f := file.Decls[0].(*FunDecl)
+	if len(f.Bounds) != 2 || f.Bounds[0] != nil || f.Bounds[1].String() != "geo.Shape" {
+		t.Fatalf("expected bounds [nil, geo.Shape], got %#v", f.Bounds)
+	}
+	if impl := file.Decls[1].(*ImplDecl); impl.Interface.String() != "geo.Shape" || impl.Type != "Rect" {
+		t.Fatalf("expected impl geo.Shape for Rect, got %#v", impl)
+	}
+}
@@ -28,6 +28,13 @@ func PrintFile(file *File) string {
 	var builder strings.Builder
 	builder.WriteString(fileStyle.Render("File") + " {\n")
 	builder.WriteString(fmt.Sprintf("  %s: %s\n", fieldStyle.Render("Package"), identStyle.Render(file.Package)))
+	if len(file.Imports) > 0 {
+		builder.WriteString(fmt.Sprintf("  %s: [\n", fieldStyle.Render("Imports")))
+		for _, imp := range file.Imports {
+			builder.WriteString(printImportDecl(imp, "    "))
+		}
+		builder.WriteString("  ]\n")
+	}
 	builder.WriteString(fmt.Sprintf("  %s: [\n", fieldStyle.Render("Declarations")))
 
 	for _, decl := range file.Decls {
@@ -40,6 +47,50 @@ func PrintFile(file *File) string {
 	return builder.String()
 }
 
+func printImportDecl(decl *ImportDecl, indent string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s%s { %s: %s", indent, declStyle.Render("ImportDecl"),
+		fieldStyle.Render("Path"), identStyle.Render(decl.Path)))
+	if len(decl.Symbols) > 0 {
+		builder.WriteString(fmt.Sprintf(", %s: [%s]", fieldStyle.Render("Symbols"),
+			identStyle.Render(strings.Join(decl.Symbols, ", "))))
+	}
+	if decl.Alias != "" {
+		builder.WriteString(fmt.Sprintf(", %s: %s", fieldStyle.Render("Alias"), identStyle.Render(decl.Alias)))
+	}
+	builder.WriteString(" }\n")
+
+	return builder.String()
+}
+
+// printType renders a type reference, or nothing for an omitted type
+func printType(t TypeExpr) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+// printTypeParams formats type parameters with their bounds: T, U: Shape
+func printTypeParams(params []string, bounds []TypeExpr) string {
+	names := make([]string, 0, len(params))
+	for i, param := range params {
+		if i < len(bounds) && bounds[i] != nil {
+			param += ": " + printType(bounds[i])
+		}
+		names = append(names, param)
+	}
+	return strings.Join(names, ", ")
+}
+
+func printTypes(types []TypeExpr) string {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, printType(t))
+	}
+	return strings.Join(names, ", ")
+}
+
 func printDecl(decl Decl, indent string) string {
 	if decl == nil {
 		return indent + "<nil decl>\n"
@@ -50,6 +101,10 @@ func printDecl(decl Decl, indent string) string {
 		return printFunDecl(d, indent)
 	case *VarDecl:
 		return printVarDecl(d, indent)
+	case *TypeDecl:
+		return printTypeDecl(d, indent)
+	case *ImplDecl:
+		return printImplDecl(d, indent)
 	default:
 		return indent + fmt.Sprintf("UnknownDecl: %T\n", decl)
 	}
@@ -59,18 +114,49 @@ func printFunDecl(decl *FunDecl, indent string) string {
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("%s%s {\n", indent, declStyle.Render("FunDecl")))
 	builder.WriteString(fmt.Sprintf("%s  %s: %s\n", indent, fieldStyle.Render("Name"), identStyle.Render(decl.Name)))
-	builder.WriteString(fmt.Sprintf("%s  %s: %s\n", indent, fieldStyle.Render("Type"), identStyle.Render(decl.Type)))
+	if decl.Exported {
+		builder.WriteString(fmt.Sprintf("%s  %s: %s\n", indent, fieldStyle.Render("Exported"), keywordStyle.Render("true")))
+	}
+	if len(decl.TypeParams) > 0 {
+		builder.WriteString(fmt.Sprintf("%s  %s: [%s]\n", indent, fieldStyle.Render("TypeParams"),
+			identStyle.Render(printTypeParams(decl.TypeParams, decl.Bounds))))
+	}
+	builder.WriteString(fmt.Sprintf("%s  %s: %s\n", indent, fieldStyle.Render("Type"), identStyle.Render(printType(decl.Type))))
 	builder.WriteString(fmt.Sprintf("%s  %s: [\n", indent, fieldStyle.Render("Params")))
 
 	for _, param := range decl.Params {
 		builder.WriteString(fmt.Sprintf("%s    %s { %s: %s, %s: %s }\n",
 			indent, keywordStyle.Render("Param"),
 			fieldStyle.Render("Name"), identStyle.Render(param.Name),
-			fieldStyle.Render("Type"), identStyle.Render(param.Type)))
+			fieldStyle.Render("Type"), identStyle.Render(printType(param.Type))))
 	}
 
 	builder.WriteString(fmt.Sprintf("%s  ]\n", indent))
-	builder.WriteString(fmt.Sprintf("%s  %s: %s", indent, fieldStyle.Render("Body"), printStmt(decl.Body, indent+"  ")))
+	// Interface methods are signatures without body
+	if decl.Body != nil {
+		builder.WriteString(fmt.Sprintf("%s  %s: %s", indent, fieldStyle.Render("Body"), printStmt(decl.Body, indent+"  ")))
+	}
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
+func printImplDecl(decl *ImplDecl, indent string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s%s {\n", indent, declStyle.Render("ImplDecl")))
+	if decl.Interface != nil {
+		builder.WriteString(fmt.Sprintf("%s  %s: %s\n", indent, fieldStyle.Render("Interface"), identStyle.Render(printType(decl.Interface))))
+	}
+	builder.WriteString(fmt.Sprintf("%s  %s: %s\n", indent, fieldStyle.Render("Type"), identStyle.Render(decl.Type)))
+	if len(decl.TypeParams) > 0 {
+		builder.WriteString(fmt.Sprintf("%s  %s: [%s]\n", indent, fieldStyle.Render("TypeParams"),
+			identStyle.Render(strings.Join(decl.TypeParams, ", "))))
+	}
+	builder.WriteString(fmt.Sprintf("%s  %s: [\n", indent, fieldStyle.Render("Methods")))
+	for _, method := range decl.Methods {
+		builder.WriteString(printFunDecl(method, indent+"    "))
+	}
+	builder.WriteString(fmt.Sprintf("%s  ]\n", indent))
 	builder.WriteString(fmt.Sprintf("%s}\n", indent))
 
 	return builder.String()
@@ -79,8 +165,15 @@ func printFunDecl(decl *FunDecl, indent string) string {
 func printVarDecl(decl *VarDecl, indent string) string {
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("%sVarDecl {\n", indent))
-	builder.WriteString(fmt.Sprintf("%s  Name: %s\n", indent, decl.Name))
-	if decl.Type != "" {
+	if len(decl.Names) > 0 {
+		builder.WriteString(fmt.Sprintf("%s  Names: (%s)\n", indent, strings.Join(decl.Names, ", ")))
+	} else {
+		builder.WriteString(fmt.Sprintf("%s  Name: %s\n", indent, decl.Name))
+	}
+	if decl.Exported {
+		builder.WriteString(fmt.Sprintf("%s  Exported: true\n", indent))
+	}
+	if decl.Type != nil {
 		builder.WriteString(fmt.Sprintf("%s  Type: %s\n", indent, decl.Type))
 	}
 	builder.WriteString(fmt.Sprintf("%s  Init: %s", indent, printExpr(decl.Init, indent+"  ")))
@@ -89,6 +182,52 @@ func printVarDecl(decl *VarDecl, indent string) string {
 	return builder.String()
 }
 
+func printTypeDecl(decl *TypeDecl, indent string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s%s {\n", indent, declStyle.Render("TypeDecl")))
+	builder.WriteString(fmt.Sprintf("%s  %s: %s\n", indent, fieldStyle.Render("Name"), identStyle.Render(decl.Name)))
+	if decl.Exported {
+		builder.WriteString(fmt.Sprintf("%s  %s: %s\n", indent, fieldStyle.Render("Exported"), keywordStyle.Render("true")))
+	}
+	if len(decl.TypeParams) > 0 {
+		builder.WriteString(fmt.Sprintf("%s  %s: [%s]\n", indent, fieldStyle.Render("TypeParams"),
+			identStyle.Render(printTypeParams(decl.TypeParams, decl.Bounds))))
+	}
+	if decl.Interface != nil {
+		builder.WriteString(fmt.Sprintf("%s  %s: [\n", indent, fieldStyle.Render("Interface")))
+		for _, method := range decl.Interface.Methods {
+			builder.WriteString(printFunDecl(method, indent+"    "))
+		}
+		builder.WriteString(fmt.Sprintf("%s  ]\n", indent))
+	}
+	if decl.Struct != nil {
+		builder.WriteString(fmt.Sprintf("%s  %s: [\n", indent, fieldStyle.Render("Fields")))
+		for _, field := range decl.Struct.Fields {
+			builder.WriteString(fmt.Sprintf("%s    %s { %s: %s, %s: %s }\n",
+				indent, keywordStyle.Render("Field"),
+				fieldStyle.Render("Name"), identStyle.Render(field.Name),
+				fieldStyle.Render("Type"), identStyle.Render(printType(field.Type))))
+		}
+		builder.WriteString(fmt.Sprintf("%s  ]\n", indent))
+	}
+	if decl.Enum != nil {
+		builder.WriteString(fmt.Sprintf("%s  %s: [\n", indent, fieldStyle.Render("Variants")))
+		for _, variant := range decl.Enum.Variants {
+			builder.WriteString(fmt.Sprintf("%s    %s { %s: %s", indent, keywordStyle.Render("Variant"),
+				fieldStyle.Render("Name"), identStyle.Render(variant.Name)))
+			if len(variant.Types) > 0 {
+				builder.WriteString(fmt.Sprintf(", %s: [%s]", fieldStyle.Render("Types"),
+					identStyle.Render(printTypes(variant.Types))))
+			}
+			builder.WriteString(" }\n")
+		}
+		builder.WriteString(fmt.Sprintf("%s  ]\n", indent))
+	}
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
 func printStmt(stmt Stmt, indent string) string {
 	if stmt == nil {
 		return indent + "<nil stmt>\n"
@@ -109,8 +248,16 @@ func printStmt(stmt Stmt, indent string) string {
 		return printIfStmt(s, indent)
 	case *WhileStmt:
 		return printWhileStmt(s, indent)
+	case *ForStmt:
+		return printForStmt(s, indent)
 	case *ForInStmt:
 		return printForInStmt(s, indent)
+	case *BranchStmt:
+		return printBranchStmt(s, indent)
+	case *MatchStmt:
+		return printMatchStmt(s, indent)
+	case *HandleStmt:
+		return printHandleStmt(s, indent)
 	default:
 		return indent + fmt.Sprintf("UnknownStmt: %T\n", stmt)
 	}
@@ -132,6 +279,7 @@ func printAssignStmt(stmt *AssignStmt, indent string) string {
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("%sAssignStmt {\n", indent))
 	builder.WriteString(fmt.Sprintf("%s  Left: %s", indent, printExpr(stmt.Left, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s  Op: %s\n", indent, stmt.Op))
 	builder.WriteString(fmt.Sprintf("%s  Right: %s", indent, printExpr(stmt.Right, indent+"  ")))
 	builder.WriteString(fmt.Sprintf("%s}\n", indent))
 
@@ -173,6 +321,9 @@ func printIfStmt(stmt *IfStmt, indent string) string {
 func printWhileStmt(stmt *WhileStmt, indent string) string {
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("%sWhileStmt {\n", indent))
+	if stmt.Label != "" {
+		builder.WriteString(fmt.Sprintf("%s  Label: %s\n", indent, stmt.Label))
+	}
 	builder.WriteString(fmt.Sprintf("%s  Cond: %s", indent, printExpr(stmt.Cond, indent+"  ")))
 	builder.WriteString(fmt.Sprintf("%s  Body: %s", indent, printStmt(stmt.Body, indent+"  ")))
 	builder.WriteString(fmt.Sprintf("%s}\n", indent))
@@ -180,9 +331,27 @@ func printWhileStmt(stmt *WhileStmt, indent string) string {
 	return builder.String()
 }
 
+func printForStmt(stmt *ForStmt, indent string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%sForStmt {\n", indent))
+	if stmt.Label != "" {
+		builder.WriteString(fmt.Sprintf("%s  Label: %s\n", indent, stmt.Label))
+	}
+	builder.WriteString(fmt.Sprintf("%s  Init: %s", indent, printStmt(stmt.Init, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s  Cond: %s", indent, printExpr(stmt.Cond, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s  Post: %s", indent, printStmt(stmt.Post, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s  Body: %s", indent, printStmt(stmt.Body, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
 func printForInStmt(stmt *ForInStmt, indent string) string {
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("%sForInStmt {\n", indent))
+	if stmt.Label != "" {
+		builder.WriteString(fmt.Sprintf("%s  Label: %s\n", indent, stmt.Label))
+	}
 	builder.WriteString(fmt.Sprintf("%s  Var: %s\n", indent, stmt.Var))
 	builder.WriteString(fmt.Sprintf("%s  Iter: %s", indent, printExpr(stmt.Iter, indent+"  ")))
 	builder.WriteString(fmt.Sprintf("%s  Body: %s", indent, printStmt(stmt.Body, indent+"  ")))
@@ -191,6 +360,45 @@ func printForInStmt(stmt *ForInStmt, indent string) string {
 	return builder.String()
 }
 
+func printBranchStmt(stmt *BranchStmt, indent string) string {
+	keyword := strings.ToLower(string(stmt.Tok))
+	if stmt.Label != "" {
+		return fmt.Sprintf("%sBranchStmt { %s %s }\n", indent, keyword, stmt.Label)
+	}
+	return fmt.Sprintf("%sBranchStmt { %s }\n", indent, keyword)
+}
+
+func printMatchStmt(stmt *MatchStmt, indent string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%sMatchStmt {\n", indent))
+	builder.WriteString(fmt.Sprintf("%s  X: %s", indent, printExpr(stmt.X, indent+"  ")))
+	for _, arm := range stmt.Arms {
+		builder.WriteString(fmt.Sprintf("%s  Arm %s(%s): %s", indent, arm.Variant,
+			strings.Join(arm.Bindings, ", "), printStmt(arm.Body, indent+"  ")))
+	}
+	if stmt.Else != nil {
+		builder.WriteString(fmt.Sprintf("%s  Else: %s", indent, printStmt(stmt.Else, indent+"  ")))
+	}
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
+func printHandleStmt(stmt *HandleStmt, indent string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%sHandleStmt {\n", indent))
+	builder.WriteString(fmt.Sprintf("%s  X: %s", indent, printExpr(stmt.X, indent+"  ")))
+	if stmt.Ok != nil {
+		builder.WriteString(fmt.Sprintf("%s  Ok(%s): %s", indent, stmt.Ok.Name, printStmt(stmt.Ok.Body, indent+"  ")))
+	}
+	if stmt.Err != nil {
+		builder.WriteString(fmt.Sprintf("%s  Err(%s): %s", indent, stmt.Err.Name, printStmt(stmt.Err.Body, indent+"  ")))
+	}
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
 func printExpr(expr Expr, indent string) string {
 	if expr == nil {
 		return indent + "<nil expr>\n"
@@ -201,14 +409,32 @@ func printExpr(expr Expr, indent string) string {
 		return printBinaryExpr(e, indent)
 	case *UnaryExpr:
 		return printUnaryExpr(e, indent)
+	case *CastExpr:
+		return printCastExpr(e, indent)
 	case *CallExpr:
 		return printCallExpr(e, indent)
+	case *SelectorExpr:
+		return printSelectorExpr(e, indent)
 	case *Ident:
 		return printIdent(e, indent)
 	case *BasicLit:
 		return printBasicLit(e, indent)
+	case *InterpolatedString:
+		return printInterpolatedString(e, indent)
 	case *ArrayLit:
 		return printArrayLit(e, indent)
+	case *FuncLit:
+		return printFuncLit(e, indent)
+	case *StructLit:
+		return printStructLit(e, indent)
+	case *TryExpr:
+		return printTryExpr(e, indent)
+	case *RangeExpr:
+		return printRangeExpr(e, indent)
+	case *TupleExpr:
+		return printTupleExpr(e, indent)
+	case *IndexExpr:
+		return printIndexExpr(e, indent)
 	default:
 		return indent + fmt.Sprintf("UnknownExpr: %T\n", expr)
 	}
@@ -235,6 +461,16 @@ func printUnaryExpr(expr *UnaryExpr, indent string) string {
 	return builder.String()
 }
 
+func printCastExpr(expr *CastExpr, indent string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("CastExpr {\n"))
+	builder.WriteString(fmt.Sprintf("%s  X: %s", indent, printExpr(expr.X, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s  Type: %s\n", indent, expr.Type))
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
 func printCallExpr(expr *CallExpr, indent string) string {
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("CallExpr {\n"))
@@ -251,6 +487,74 @@ func printCallExpr(expr *CallExpr, indent string) string {
 	return builder.String()
 }
 
+func printSelectorExpr(expr *SelectorExpr, indent string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("SelectorExpr {\n"))
+	builder.WriteString(fmt.Sprintf("%s  X: %s", indent, printExpr(expr.X, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s  Sel: %s\n", indent, identStyle.Render(expr.Sel)))
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
+func printTryExpr(expr *TryExpr, indent string) string {
+	var builder strings.Builder
+	builder.WriteString("TryExpr {\n")
+	builder.WriteString(fmt.Sprintf("%s  X: %s", indent, printExpr(expr.X, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
+func printTupleExpr(expr *TupleExpr, indent string) string {
+	var builder strings.Builder
+	builder.WriteString("TupleExpr {\n")
+	builder.WriteString(fmt.Sprintf("%s  Elems: [\n", indent))
+	for _, elem := range expr.Elems {
+		builder.WriteString(fmt.Sprintf("%s    %s", indent, printExpr(elem, indent+"    ")))
+	}
+	builder.WriteString(fmt.Sprintf("%s  ]\n", indent))
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
+func printFuncLit(expr *FuncLit, indent string) string {
+	var builder strings.Builder
+	builder.WriteString("FuncLit {\n")
+	builder.WriteString(fmt.Sprintf("%s  Type: %s\n", indent, printType(expr.Type)))
+	builder.WriteString(fmt.Sprintf("%s  Params: [\n", indent))
+	for _, param := range expr.Params {
+		builder.WriteString(fmt.Sprintf("%s    Param { Name: %s, Type: %s }\n", indent, param.Name, printType(param.Type)))
+	}
+	builder.WriteString(fmt.Sprintf("%s  ]\n", indent))
+	builder.WriteString(fmt.Sprintf("%s  Body: %s", indent, printStmt(expr.Body, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
+func printRangeExpr(expr *RangeExpr, indent string) string {
+	var builder strings.Builder
+	builder.WriteString("RangeExpr {\n")
+	builder.WriteString(fmt.Sprintf("%s  Inclusive: %t\n", indent, expr.Inclusive))
+	builder.WriteString(fmt.Sprintf("%s  Low: %s", indent, printExpr(expr.Low, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s  High: %s", indent, printExpr(expr.High, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
+func printIndexExpr(expr *IndexExpr, indent string) string {
+	var builder strings.Builder
+	builder.WriteString("IndexExpr {\n")
+	builder.WriteString(fmt.Sprintf("%s  X: %s", indent, printExpr(expr.X, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s  Index: %s", indent, printExpr(expr.Index, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
 func printIdent(expr *Ident, indent string) string {
 	return fmt.Sprintf("%s { %s: %s }\n",
 		exprStyle.Render("Ident"),
@@ -267,6 +571,21 @@ func printBasicLit(expr *BasicLit, indent string) string {
 		literalStyle.Render(expr.Value))
 }
 
+func printInterpolatedString(expr *InterpolatedString, indent string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("InterpolatedString {\n"))
+	builder.WriteString(fmt.Sprintf("%s  Parts: [\n", indent))
+
+	for _, part := range expr.Parts {
+		builder.WriteString(fmt.Sprintf("%s    %s", indent, printExpr(part, indent+"    ")))
+	}
+
+	builder.WriteString(fmt.Sprintf("%s  ]\n", indent))
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}
+
 func printArrayLit(expr *ArrayLit, indent string) string {
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("ArrayLit {\n"))
@@ -281,3 +600,19 @@ func printArrayLit(expr *ArrayLit, indent string) string {
 
 	return builder.String()
 }
+
+func printStructLit(expr *StructLit, indent string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("StructLit {\n"))
+	builder.WriteString(fmt.Sprintf("%s  Type: %s", indent, printExpr(expr.Type, indent+"  ")))
+	builder.WriteString(fmt.Sprintf("%s  Fields: [\n", indent))
+
+	for _, field := range expr.Fields {
+		builder.WriteString(fmt.Sprintf("%s    %s: %s", indent, fieldStyle.Render(field.Name), printExpr(field.Value, indent+"    ")))
+	}
+
+	builder.WriteString(fmt.Sprintf("%s  ]\n", indent))
+	builder.WriteString(fmt.Sprintf("%s}\n", indent))
+
+	return builder.String()
+}